@@ -0,0 +1,9 @@
+-- +migrate Up
+create table proof_nullifiers(
+    hash          bytea primary key,
+    document_hash text not null,
+    created_at    timestamp default now()
+);
+
+-- +migrate Down
+drop table proof_nullifiers;
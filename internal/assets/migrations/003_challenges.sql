@@ -0,0 +1,15 @@
+-- +migrate Up
+create table challenges(
+    nonce        text primary key,
+    user_did     text not null,
+    user_address bytea not null,
+    expires_at   timestamp not null,
+    created_at   timestamp default now()
+);
+
+create index challenges_expires_at_idx on challenges(expires_at);
+create index challenges_user_did_idx on challenges(user_did);
+create index challenges_user_address_idx on challenges(user_address);
+
+-- +migrate Down
+drop table challenges;
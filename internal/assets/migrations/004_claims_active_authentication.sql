@@ -0,0 +1,5 @@
+-- +migrate Up
+alter table claims add column active_authentication boolean not null default false;
+
+-- +migrate Down
+alter table claims drop column active_authentication;
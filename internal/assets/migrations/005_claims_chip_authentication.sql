@@ -0,0 +1,9 @@
+-- +migrate Up
+alter table claims add column chip_authentication boolean not null default false;
+alter table challenges add column ca_terminal_curve text;
+alter table challenges add column ca_terminal_key text;
+
+-- +migrate Down
+alter table challenges drop column ca_terminal_key;
+alter table challenges drop column ca_terminal_curve;
+alter table claims drop column chip_authentication;
@@ -0,0 +1,5 @@
+-- +migrate Up
+alter table claims add column policy text not null default 'default';
+
+-- +migrate Down
+alter table claims drop column policy;
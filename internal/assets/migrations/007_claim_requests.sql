@@ -0,0 +1,30 @@
+-- +migrate Up
+create table claim_requests(
+    id                    uuid primary key,
+    status                text not null default 'pending',
+    user_id               uuid not null,
+    user_did              text not null,
+    user_address          bytea not null,
+    document_hash         text not null,
+    issuing_authority     bigint not null,
+    is_adult              boolean not null,
+    expiration            timestamp,
+    dg2_hash              bytea not null,
+    policy                text not null,
+    active_authentication boolean not null default false,
+    chip_authentication   boolean not null default false,
+    claim_id              uuid,
+    issuer_did            text,
+    attempts              integer not null default 0,
+    next_attempt_at       timestamp not null default now(),
+    leased_until          timestamp,
+    last_error            text,
+    created_at            timestamp not null default now(),
+    updated_at            timestamp not null default now()
+);
+
+create index claim_requests_pending_idx on claim_requests(next_attempt_at) where status = 'pending';
+create index claim_requests_document_hash_idx on claim_requests(document_hash);
+
+-- +migrate Down
+drop table claim_requests;
@@ -0,0 +1,31 @@
+-- +migrate Up
+alter table claims add column ds_serial text not null default '';
+alter table claims add column ds_country text not null default '';
+alter table claim_requests add column ds_serial text not null default '';
+alter table claim_requests add column ds_country text not null default '';
+
+create index claims_ds_serial_idx on claims(ds_serial);
+
+create table revocations(
+    id               uuid primary key,
+    claim_id         uuid,
+    claim_request_id uuid,
+    user_id          uuid not null,
+    document_hash    text not null,
+    revocation_nonce bigint,
+    reason           text not null,
+    operator         text not null,
+    selector         text not null,
+    status           text not null default 'pending',
+    created_at       timestamp not null default now()
+);
+
+create index revocations_claim_id_idx on revocations(claim_id);
+
+-- +migrate Down
+drop table revocations;
+
+alter table claim_requests drop column ds_country;
+alter table claim_requests drop column ds_serial;
+alter table claims drop column ds_country;
+alter table claims drop column ds_serial;
@@ -0,0 +1,17 @@
+-- +migrate Up
+alter table claims add column status text not null default 'active';
+alter table claims add column revocation_nonce bigint;
+alter table claims add column expires_at timestamp;
+
+update claims set status = 'revoked' where id in (select claim_id from revocations where status = 'done');
+
+create index claims_user_did_idx on claims(user_did);
+create index claims_user_id_idx on claims(user_id);
+
+-- +migrate Down
+drop index claims_user_id_idx;
+drop index claims_user_did_idx;
+
+alter table claims drop column expires_at;
+alter table claims drop column revocation_nonce;
+alter table claims drop column status;
@@ -3,6 +3,7 @@ package cli
 import (
 	"github.com/rarimo/passport-identity-provider/internal/config"
 	"github.com/rarimo/passport-identity-provider/internal/service"
+	"github.com/rarimo/passport-identity-provider/internal/service/revocation"
 	"gitlab.com/distributed_lab/logan/v3"
 
 	"github.com/alecthomas/kingpin"
@@ -30,6 +31,24 @@ func Run(args []string) bool {
 	migrateUpCmd := migrateCmd.Command("up", "migrate db up")
 	migrateDownCmd := migrateCmd.Command("down", "migrate db down")
 
+	masterListCmd := app.Command("master-list", "CSCA master list command")
+	masterListValidateCmd := masterListCmd.Command("validate", "verify master list signature and signer")
+	masterListValidatePath := masterListValidateCmd.Arg("path", "DER-encoded master list (.ml) path").Required().ExistingFile()
+	masterListValidateAnchors := masterListValidateCmd.Flag("anchors", "PEM bundle the master list signer must chain to").Required().ExistingFile()
+	masterListConvertCmd := masterListCmd.Command("convert", "verify master list and write its certificates as PEM")
+	masterListConvertPath := masterListConvertCmd.Arg("path", "DER-encoded master list (.ml) path").Required().ExistingFile()
+	masterListConvertAnchors := masterListConvertCmd.Flag("anchors", "PEM bundle the master list signer must chain to").Required().ExistingFile()
+	masterListConvertOut := masterListConvertCmd.Flag("out", "output PEM path, stdout by default").String()
+
+	revokeCmd := app.Command("revoke", "revoke the claims on the issuer node and record the revocations")
+	revokeOpts := RevokeOptions{}
+	revokeCmd.Flag("claim-id", "claim ID").StringVar(&revokeOpts.ClaimID)
+	revokeCmd.Flag("user-id", "user ID, all of its claims are revoked").StringVar(&revokeOpts.UserID)
+	revokeCmd.Flag("document-hash", "document hash, all of its claims are revoked").StringVar(&revokeOpts.DocumentHash)
+	revokeCmd.Flag("ds-serial", "hex serial number of the document signer certificate").StringVar(&revokeOpts.DSSerial)
+	revokeCmd.Flag("ds-country", "document signer certificate country narrowing --ds-serial").StringVar(&revokeOpts.DSCountry)
+	revokeCmd.Flag("reason", "revocation reason").Required().EnumVar(&revokeOpts.Reason, revocation.Reasons...)
+
 	// custom commands go here...
 
 	cmd, err := app.Parse(args[1:])
@@ -45,6 +64,12 @@ func Run(args []string) bool {
 		err = MigrateUp(cfg)
 	case migrateDownCmd.FullCommand():
 		err = MigrateDown(cfg)
+	case masterListValidateCmd.FullCommand():
+		err = ValidateMasterList(log, *masterListValidatePath, *masterListValidateAnchors)
+	case masterListConvertCmd.FullCommand():
+		err = ConvertMasterList(log, *masterListConvertPath, *masterListConvertAnchors, *masterListConvertOut)
+	case revokeCmd.FullCommand():
+		err = RevokeClaims(cfg, revokeOpts)
 	// handle any custom commands here in the same way
 	default:
 		log.Errorf("unknown command %s", cmd)
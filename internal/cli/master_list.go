@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"encoding/pem"
+	"io"
+	"os"
+
+	"github.com/rarimo/certificate-transparency-go/x509"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/algorithms"
+	"github.com/rarimo/passport-identity-provider/internal/service/certificates"
+	"github.com/rarimo/passport-identity-provider/internal/service/masterlist"
+)
+
+// ValidateMasterList verifies the master list signature and signer certificate
+// and reports the listed certificates.
+func ValidateMasterList(log *logan.Entry, path, anchorsPath string) error {
+	ml, err := readMasterList(path, anchorsPath)
+	if err != nil {
+		return err
+	}
+
+	for _, err := range ml.Skipped {
+		log.WithError(err).Warn("skipped master list certificate")
+	}
+
+	log.WithFields(logan.F{
+		"version": ml.Version,
+		"signer":  ml.Signer.Subject.String(),
+		"valid":   len(ml.Certificates),
+		"skipped": len(ml.Skipped),
+	}).Info("master list is valid")
+	return nil
+}
+
+// ConvertMasterList writes the certificates of the verified master list as a PEM bundle
+// usable as master_certs_path, outPath defaults to the standard output.
+func ConvertMasterList(log *logan.Entry, path, anchorsPath, outPath string) error {
+	ml, err := readMasterList(path, anchorsPath)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if outPath != "" {
+		file, err := os.Create(outPath)
+		if err != nil {
+			return errors.Wrap(err, "failed to create output file")
+		}
+		defer file.Close()
+
+		out = file
+	}
+
+	for _, cert := range ml.Certificates {
+		if err := pem.Encode(out, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return errors.Wrap(err, "failed to write certificate")
+		}
+	}
+
+	log.WithFields(logan.F{
+		"converted": len(ml.Certificates),
+		"skipped":   len(ml.Skipped),
+	}).Info("master list converted")
+	return nil
+}
+
+func readMasterList(path, anchorsPath string) (*masterlist.MasterList, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read master list")
+	}
+
+	ml, err := masterlist.Parse(raw, algorithms.NewDefaultRegistry())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse master list")
+	}
+
+	rawAnchors, err := os.ReadFile(anchorsPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read master list anchors")
+	}
+
+	anchorCerts, errs := certificates.ParsePEMBundle(rawAnchors)
+	if len(errs) != 0 {
+		return nil, errors.Wrap(errs[0], "failed to parse master list anchors")
+	}
+	if len(anchorCerts) == 0 {
+		return nil, errors.New("no master list anchors found")
+	}
+
+	anchors := x509.NewCertPool()
+	for _, cert := range anchorCerts {
+		anchors.AddCert(cert)
+	}
+
+	if err := ml.VerifySigner(anchors); err != nil {
+		return nil, errors.Wrap(err, "failed to verify master list signer")
+	}
+
+	return ml, nil
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"os/user"
+
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data/pg"
+	"github.com/rarimo/passport-identity-provider/internal/service"
+	"github.com/rarimo/passport-identity-provider/internal/service/revocation"
+	"github.com/rarimo/passport-identity-provider/internal/service/vault"
+)
+
+// RevokeOptions are the revoke command flags, the empty selectors are not set.
+type RevokeOptions struct {
+	ClaimID      string
+	UserID       string
+	DocumentHash string
+	DSSerial     string
+	DSCountry    string
+	Reason       string
+}
+
+// RevokeClaims revokes the credentials of the selected claims on the issuer node and records
+// the revocations with the reason and the system user running the command as the operator.
+func RevokeClaims(cfg config.Config, opts RevokeOptions) error {
+	selector, err := opts.selector()
+	if err != nil {
+		return err
+	}
+	if err := selector.Validate(); err != nil {
+		return err
+	}
+
+	operator, err := cliOperator()
+	if err != nil {
+		return err
+	}
+
+	// the memory issuer credentials live in the service process
+	if cfg.IssuerConfig().Backend == config.IssuerBackendMemory {
+		return errors.New("claims of the memory issuer can only be revoked by the service admin API")
+	}
+
+	vaultClient, err := vault.NewVaultClient(cfg.VaultConfig())
+	if err != nil {
+		return errors.Wrap(err, "failed to init new vault client")
+	}
+
+	iss, err := service.NewIssuer(cfg, vaultClient)
+	if err != nil {
+		return errors.Wrap(err, "failed to init issuer")
+	}
+
+	revocations, err := revocation.NewRevoker(pg.NewMasterQ(cfg.DB()), iss).Revoke(selector, opts.Reason, operator)
+	for _, revoked := range revocations {
+		cfg.Log().WithFields(logan.F{
+			"claim_id":         revoked.ClaimID,
+			"claim_request":    revoked.ClaimRequestID,
+			"user_id":          revoked.UserID,
+			"revocation_nonce": revoked.RevocationNonce,
+		}).Info("claim revoked")
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke claims", logan.F{"revoked": len(revocations)})
+	}
+
+	cfg.Log().WithFields(logan.F{
+		"selector": selector.String(),
+		"revoked":  len(revocations),
+	}).Info("claims revoked")
+	return nil
+}
+
+// cliOperator is the operator of the command line revocations, the system user running it
+// with the service config and database credentials.
+func cliOperator() (string, error) {
+	current, err := user.Current()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get system user")
+	}
+
+	return "cli:" + current.Username, nil
+}
+
+func (o RevokeOptions) selector() (revocation.Selector, error) {
+	var (
+		selector revocation.Selector
+		err      error
+	)
+
+	if selector.ClaimID, err = optionalUUID(o.ClaimID); err != nil {
+		return selector, errors.Wrap(err, "invalid claim ID")
+	}
+	if selector.UserID, err = optionalUUID(o.UserID); err != nil {
+		return selector, errors.Wrap(err, "invalid user ID")
+	}
+	selector.DocumentHash = optionalString(o.DocumentHash)
+	selector.DSSerial = optionalString(o.DSSerial)
+	selector.DSCountry = optionalString(o.DSCountry)
+
+	return selector, nil
+}
+
+func optionalUUID(value string) (*uuid.UUID, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type AdminConfiger interface {
+	AdminConfig() *AdminConfig
+}
+
+// AdminConfig lists the operators of the admin endpoints, they are disabled when there are none.
+type AdminConfig struct {
+	Operators []OperatorConfig `fig:"operators"`
+}
+
+// OperatorConfig is the bearer token of the operator, the actions taken with it are audited
+// under the operator name.
+type OperatorConfig struct {
+	Name  string `fig:"name,required"`
+	Token string `fig:"token,required"`
+}
+
+type admin struct {
+	once   comfig.Once
+	getter kv.Getter
+}
+
+func NewAdminConfiger(getter kv.Getter) AdminConfiger {
+	return &admin{
+		getter: getter,
+	}
+}
+
+func (a *admin) AdminConfig() *AdminConfig {
+	return a.once.Do(func() interface{} {
+		var result AdminConfig
+
+		raw, err := a.getter.GetStringMap("admin")
+		if err != nil {
+			panic(err)
+		}
+
+		err = figure.
+			Out(&result).
+			With(figure.BaseHooks).
+			From(raw).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		names, tokens := make(map[string]bool), make(map[string]bool)
+		for _, operator := range result.Operators {
+			if names[operator.Name] || tokens[operator.Token] {
+				panic(errors.New("operator names and tokens must be unique"))
+			}
+			names[operator.Name], tokens[operator.Token] = true, true
+		}
+
+		return &result
+	}).(*AdminConfig)
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type ChallengeConfiger interface {
+	ChallengeConfig() *ChallengeConfig
+}
+
+// ChallengeConfig configures the registration challenges issued by GET /v1/challenge.
+type ChallengeConfig struct {
+	TTL time.Duration `fig:"ttl"`
+	// Required rejects the registrations that do not commit to a challenge, it is off by
+	// default as the bundled circuits have no challenge public signal
+	Required bool `fig:"required"`
+	// MaxOutstanding caps the challenges not expired yet of a user DID and of a user address
+	MaxOutstanding int `fig:"max_outstanding"`
+	// CleanupPeriod is how often the expired challenges are deleted
+	CleanupPeriod time.Duration `fig:"cleanup_period"`
+}
+
+type challenge struct {
+	once   comfig.Once
+	getter kv.Getter
+}
+
+func NewChallengeConfiger(getter kv.Getter) ChallengeConfiger {
+	return &challenge{
+		getter: getter,
+	}
+}
+
+func (c *challenge) ChallengeConfig() *ChallengeConfig {
+	return c.once.Do(func() interface{} {
+		result := ChallengeConfig{
+			TTL:            5 * time.Minute,
+			MaxOutstanding: 5,
+			CleanupPeriod:  time.Minute,
+		}
+
+		raw, err := c.getter.GetStringMap("challenge")
+		if err != nil {
+			panic(err)
+		}
+
+		err = figure.
+			Out(&result).
+			With(figure.BaseHooks).
+			From(raw).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		if result.TTL <= 0 || result.CleanupPeriod <= 0 {
+			panic(errors.New("ttl and cleanup_period must be positive"))
+		}
+		if result.MaxOutstanding < 1 {
+			panic(errors.New("max_outstanding must be 1 at least"))
+		}
+
+		return &result
+	}).(*ChallengeConfig)
+}
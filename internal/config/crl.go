@@ -0,0 +1,66 @@
+package config
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+type CRLConfiger interface {
+	CRLConfig() *CRLConfig
+}
+
+// CRLConfig lists the sources of the document signer certificate revocation lists,
+// the revocation checks are disabled when no source is set.
+type CRLConfig struct {
+	Dir            string        `fig:"dir"`
+	URLs           []string      `fig:"urls"`
+	RefreshPeriod  time.Duration `fig:"refresh_period"`
+	RequestTimeout time.Duration `fig:"request_timeout"`
+	// FailOpen accepts the certificates of the issuers without a valid CRL or with the CRL
+	// past its next update, they are rejected by default
+	FailOpen bool `fig:"fail_open"`
+}
+
+type crl struct {
+	once   comfig.Once
+	getter kv.Getter
+}
+
+func NewCRLConfiger(getter kv.Getter) CRLConfiger {
+	return &crl{
+		getter: getter,
+	}
+}
+
+func (c *crl) CRLConfig() *CRLConfig {
+	return c.once.Do(func() interface{} {
+		result := CRLConfig{
+			RefreshPeriod:  time.Hour,
+			RequestTimeout: 30 * time.Second,
+		}
+
+		raw, err := c.getter.GetStringMap("crl")
+		if err != nil {
+			panic(err)
+		}
+
+		err = figure.
+			Out(&result).
+			With(figure.BaseHooks).
+			From(raw).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		return &result
+	}).(*CRLConfig)
+}
+
+// Enabled reports whether any CRL source is configured.
+func (c *CRLConfig) Enabled() bool {
+	return c.Dir != "" || len(c.URLs) != 0
+}
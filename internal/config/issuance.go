@@ -0,0 +1,76 @@
+package config
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type IssuanceConfiger interface {
+	IssuanceConfig() *IssuanceConfig
+}
+
+// IssuanceConfig configures the worker issuing the claims of the accepted registrations.
+type IssuanceConfig struct {
+	// PollPeriod is how often the worker looks for the due claim requests
+	PollPeriod time.Duration `fig:"poll_period"`
+	// MaxAttempts is how many times the claim issuance is attempted before the request fails
+	MaxAttempts int           `fig:"max_attempts"`
+	MinBackoff  time.Duration `fig:"min_backoff"`
+	MaxBackoff  time.Duration `fig:"max_backoff"`
+	// Lease is how long an attempt holds the claim request and its document, it is picked up
+	// again after the lease if the worker dies, so it must outlast the issuer calls of the attempt
+	Lease time.Duration `fig:"lease"`
+}
+
+type issuance struct {
+	once   comfig.Once
+	getter kv.Getter
+}
+
+func NewIssuanceConfiger(getter kv.Getter) IssuanceConfiger {
+	return &issuance{
+		getter: getter,
+	}
+}
+
+func (i *issuance) IssuanceConfig() *IssuanceConfig {
+	return i.once.Do(func() interface{} {
+		result := IssuanceConfig{
+			PollPeriod:  time.Second,
+			MaxAttempts: 10,
+			MinBackoff:  5 * time.Second,
+			MaxBackoff:  10 * time.Minute,
+			Lease:       5 * time.Minute,
+		}
+
+		raw, err := i.getter.GetStringMap("issuance")
+		if err != nil {
+			panic(err)
+		}
+
+		err = figure.
+			Out(&result).
+			With(figure.BaseHooks).
+			From(raw).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		if result.PollPeriod <= 0 || result.Lease <= 0 {
+			panic(errors.New("poll_period and lease must be positive"))
+		}
+		if result.MaxAttempts < 1 {
+			panic(errors.New("max_attempts must be 1 at least"))
+		}
+		if result.MinBackoff <= 0 || result.MaxBackoff < result.MinBackoff {
+			panic(errors.New("min_backoff must be positive and max_backoff must not be less"))
+		}
+
+		return &result
+	}).(*IssuanceConfig)
+}
@@ -8,17 +8,39 @@ import (
 	"gitlab.com/distributed_lab/kit/kv"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 	"reflect"
+	"time"
 )
 
 type IssuerConfiger interface {
 	IssuerConfig() *IssuerConfig
 }
 
+// Issuer backends
+const (
+	IssuerBackendNode   = "node"
+	IssuerBackendMemory = "memory"
+)
+
 type IssuerConfig struct {
-	BaseUrl          string   `fig:"base_url,required"`
+	// Backend is the remote issuer node or the in-process memory issuer for the offline runs
+	Backend          string   `fig:"backend"`
+	BaseUrl          string   `fig:"base_url"`
 	DID              *w3c.DID `fig:"did,required"`
 	ClaimType        string   `fig:"claim_type,required"`
 	CredentialSchema string   `fig:"credential_schema,required"`
+	// MaxCredentialLifetime caps the credential expiration, which is the document expiration
+	// otherwise, zero means no cap
+	MaxCredentialLifetime time.Duration `fig:"max_credential_lifetime"`
+	// Timeout is the timeout of every issuer node request attempt
+	Timeout time.Duration `fig:"timeout"`
+	// RetryAttempts is how many times the safe and idempotent requests are retried
+	RetryAttempts   int           `fig:"retry_attempts"`
+	RetryMinBackoff time.Duration `fig:"retry_min_backoff"`
+	RetryMaxBackoff time.Duration `fig:"retry_max_backoff"`
+	// BreakerThreshold is how many failed calls in a row open the circuit breaker, zero disables it
+	BreakerThreshold int `fig:"breaker_threshold"`
+	// BreakerCooldown is how long the open circuit breaker rejects the calls
+	BreakerCooldown time.Duration `fig:"breaker_cooldown"`
 }
 
 type issuer struct {
@@ -34,7 +56,15 @@ func NewIssuerConfiger(getter kv.Getter) IssuerConfiger {
 
 func (i *issuer) IssuerConfig() *IssuerConfig {
 	return i.once.Do(func() interface{} {
-		var result IssuerConfig
+		result := IssuerConfig{
+			Backend:          IssuerBackendNode,
+			Timeout:          10 * time.Second,
+			RetryAttempts:    2,
+			RetryMinBackoff:  200 * time.Millisecond,
+			RetryMaxBackoff:  2 * time.Second,
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+		}
 
 		err := figure.
 			Out(&result).
@@ -45,6 +75,30 @@ func (i *issuer) IssuerConfig() *IssuerConfig {
 			panic(err)
 		}
 
+		switch result.Backend {
+		case IssuerBackendNode:
+			if result.BaseUrl == "" {
+				panic(errors.New("base_url is required for the node issuer"))
+			}
+		case IssuerBackendMemory:
+		default:
+			panic(errors.New("unknown issuer backend " + result.Backend))
+		}
+
+		if result.MaxCredentialLifetime < 0 {
+			panic(errors.New("max_credential_lifetime must not be negative"))
+		}
+		if result.Timeout <= 0 {
+			panic(errors.New("timeout must be positive"))
+		}
+		if result.RetryAttempts < 0 || result.BreakerThreshold < 0 {
+			panic(errors.New("retry_attempts and breaker_threshold must not be negative"))
+		}
+		// the jitter is taken from the half of the backoff, so it must be a few nanoseconds at least
+		if result.RetryMinBackoff < time.Millisecond || result.RetryMaxBackoff < result.RetryMinBackoff {
+			panic(errors.New("retry_min_backoff must be 1ms at least and retry_max_backoff must not be less"))
+		}
+
 		return &result
 	}).(*IssuerConfig)
 }
@@ -18,6 +18,11 @@ type Config interface {
 	VerifierConfiger
 	NetworkConfiger
 	VaultConfiger
+	CRLConfiger
+	AdminConfiger
+	ChallengeConfiger
+	PoliciesConfiger
+	IssuanceConfiger
 }
 
 type config struct {
@@ -31,18 +36,28 @@ type config struct {
 	VerifierConfiger
 	NetworkConfiger
 	VaultConfiger
+	CRLConfiger
+	AdminConfiger
+	ChallengeConfiger
+	PoliciesConfiger
+	IssuanceConfiger
 }
 
 func New(getter kv.Getter) Config {
 	return &config{
-		getter:           getter,
-		Databaser:        pgdb.NewDatabaser(getter),
-		Copuser:          copus.NewCopuser(getter),
-		Listenerer:       comfig.NewListenerer(getter),
-		Logger:           comfig.NewLogger(getter, comfig.LoggerOpts{}),
-		IssuerConfiger:   NewIssuerConfiger(getter),
-		VerifierConfiger: NewVerifierConfiger(getter),
-		NetworkConfiger:  NewNetworkConfiger(getter),
-		VaultConfiger:    NewVaultConfiger(getter),
+		getter:            getter,
+		Databaser:         pgdb.NewDatabaser(getter),
+		Copuser:           copus.NewCopuser(getter),
+		Listenerer:        comfig.NewListenerer(getter),
+		Logger:            comfig.NewLogger(getter, comfig.LoggerOpts{}),
+		IssuerConfiger:    NewIssuerConfiger(getter),
+		VerifierConfiger:  NewVerifierConfiger(getter),
+		NetworkConfiger:   NewNetworkConfiger(getter),
+		VaultConfiger:     NewVaultConfiger(getter),
+		CRLConfiger:       NewCRLConfiger(getter),
+		AdminConfiger:     NewAdminConfiger(getter),
+		ChallengeConfiger: NewChallengeConfiger(getter),
+		PoliciesConfiger:  NewPoliciesConfiger(getter),
+		IssuanceConfiger:  NewIssuanceConfiger(getter),
 	}
 }
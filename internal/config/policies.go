@@ -0,0 +1,62 @@
+package config
+
+import (
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+type PoliciesConfiger interface {
+	PoliciesConfig() *PoliciesConfig
+}
+
+// PoliciesConfig declares the eligibility policies the registrations select. Without
+// the policies the only one is the default policy of the verifier allowed age.
+type PoliciesConfig struct {
+	// Default is the policy of the registrations that do not select one
+	Default  string         `fig:"default"`
+	Policies []PolicyConfig `fig:"list"`
+}
+
+// PolicyConfig is the eligibility policy rule set, the rules that are not set allow anyone.
+type PolicyConfig struct {
+	Name   string `fig:"name,required"`
+	MinAge int    `fig:"min_age"`
+	// Countries are the document signer certificate countries, e.g. UA
+	Countries []string `fig:"countries"`
+	// DocumentTypes are the MRZ document codes, e.g. P or ID, the circuit must commit to it
+	DocumentTypes []string `fig:"document_types"`
+}
+
+type policies struct {
+	once   comfig.Once
+	getter kv.Getter
+}
+
+func NewPoliciesConfiger(getter kv.Getter) PoliciesConfiger {
+	return &policies{
+		getter: getter,
+	}
+}
+
+func (p *policies) PoliciesConfig() *PoliciesConfig {
+	return p.once.Do(func() interface{} {
+		var result PoliciesConfig
+
+		raw, err := p.getter.GetStringMap("policies")
+		if err != nil {
+			panic(err)
+		}
+
+		err = figure.
+			Out(&result).
+			With(figure.BaseHooks).
+			From(raw).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		return &result
+	}).(*PoliciesConfig)
+}
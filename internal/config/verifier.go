@@ -2,11 +2,14 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"gitlab.com/distributed_lab/figure/v3"
 	"gitlab.com/distributed_lab/kit/comfig"
 	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3/errors"
 )
 
 type VerifierConfiger interface {
@@ -14,10 +17,76 @@ type VerifierConfiger interface {
 }
 
 type VerifierConfig struct {
-	VerificationKeys    map[string][]byte
-	MasterCerts         []byte
-	AllowedAge          int
-	RegistrationTimeout time.Duration
+	// VerificationKeysPaths are the legacy default circuits by their signature algorithm keys
+	VerificationKeysPaths map[string]string `fig:"verification_keys_paths"`
+	Circuits              []CircuitConfig   `fig:"circuits"`
+	MasterCertsPath       string            `fig:"master_certs_path"`
+	// MasterListsPaths are the signed CMS CSCA master lists
+	MasterListsPaths []string `fig:"master_lists_paths"`
+	// MasterListAnchorsPath is the PEM bundle the master list signers must chain to,
+	// it is required with the master lists
+	MasterListAnchorsPath string        `fig:"master_list_anchors_path"`
+	AllowedAge            int           `fig:"allowed_age,required"`
+	RegistrationTimeout   time.Duration `fig:"registration_timeout"`
+	// CurrentDateWindow is how far the proof current date may be from now, as the proof
+	// is generated in the user time zone
+	CurrentDateWindow time.Duration `fig:"current_date_window"`
+	// ExpiryGraceWindow is how long the document must remain valid, the documents expiring
+	// within it are rejected as the expired ones
+	ExpiryGraceWindow time.Duration `fig:"expiry_grace_window"`
+	// Backend is the Groth16 verifier backend, native or rapidsnark
+	Backend string `fig:"backend"`
+	// ActiveAuthentication is the active authentication mode: required, optional or ignored
+	ActiveAuthentication string `fig:"active_authentication"`
+	// ActiveAuthenticationCountries override the mode for the document signer countries
+	ActiveAuthenticationCountries map[string]string `fig:"active_authentication_countries"`
+	// WatchFiles enables reloading the verification keys and trust anchors on the files change
+	WatchFiles bool `fig:"watch_files"`
+}
+
+// CircuitConfig declares a registration circuit version.
+type CircuitConfig struct {
+	ID string `fig:"id,required"`
+	// Algorithm is the circuit key of the signature algorithms, e.g. sha256 or sha256_rsapss
+	Algorithm           string     `fig:"algorithm,required"`
+	VerificationKeyPath string     `fig:"verification_key_path,required"`
+	Default             bool       `fig:"default"`
+	DeprecatedAt        *time.Time `fig:"deprecated_at"`
+	// PubSignals are the public signals schema in the proof order, the first circuits layout by default
+	PubSignals []PubSignalConfig `fig:"pub_signals"`
+}
+
+// PubSignalConfig declares a public signal of the circuit.
+type PubSignalConfig struct {
+	Name string `fig:"name,required"`
+	// Type is one of field, uint, year, month, day or document_code
+	Type string `fig:"type,required"`
+}
+
+// VerifierFiles are the verification keys and trust anchors read from the configured paths.
+type VerifierFiles struct {
+	// VerificationKeys are the circuit verification keys by the circuit IDs
+	VerificationKeys map[string][]byte
+	MasterCerts      []byte
+	// MasterLists are the master lists by their paths
+	MasterLists       map[string][]byte
+	MasterListAnchors []byte
+}
+
+// Active authentication modes
+const (
+	ActiveAuthenticationRequired = "required"
+	ActiveAuthenticationOptional = "optional"
+	ActiveAuthenticationIgnored  = "ignored"
+)
+
+func validateActiveAuthenticationMode(mode string) error {
+	switch mode {
+	case ActiveAuthenticationRequired, ActiveAuthenticationOptional, ActiveAuthenticationIgnored:
+		return nil
+	default:
+		return errors.New("mode must be required, optional or ignored, got " + mode)
+	}
 }
 
 type verifier struct {
@@ -33,15 +102,13 @@ func NewVerifierConfiger(getter kv.Getter) VerifierConfiger {
 
 func (v *verifier) VerifierConfig() *VerifierConfig {
 	return v.once.Do(func() interface{} {
-		newCfg := struct {
-			VerificationKeysPaths map[string]string `fig:"verification_keys_paths,required"`
-			MasterCertsPath       string            `fig:"master_certs_path,required"`
-			AllowedAge            int               `fig:"allowed_age,required"`
-			RegistrationTimeout   time.Duration     `fig:"registration_timeout"`
-		}{}
+		result := VerifierConfig{
+			ActiveAuthentication: ActiveAuthenticationOptional,
+			CurrentDateWindow:    24 * time.Hour,
+		}
 
 		err := figure.
-			Out(&newCfg).
+			Out(&result).
 			With(figure.BaseHooks).
 			From(kv.MustGetStringMap(v.getter, "verifier")).
 			Please()
@@ -49,26 +116,130 @@ func (v *verifier) VerifierConfig() *VerifierConfig {
 			panic(err)
 		}
 
-		verificationKeys := make(map[string][]byte)
-		for algo, path := range newCfg.VerificationKeysPaths {
-			verificationKey, err := os.ReadFile(path)
-			if err != nil {
-				panic(err)
+		if len(result.VerificationKeysPaths) == 0 && len(result.Circuits) == 0 {
+			panic(errors.New("either verification_keys_paths or circuits must be set"))
+		}
+
+		if result.MasterCertsPath == "" && len(result.MasterListsPaths) == 0 {
+			panic(errors.New("either master_certs_path or master_lists_paths must be set"))
+		}
+		if len(result.MasterListsPaths) != 0 && result.MasterListAnchorsPath == "" {
+			panic(errors.New("master_list_anchors_path is required with master_lists_paths"))
+		}
+
+		if result.CurrentDateWindow < 0 {
+			panic(errors.New("current_date_window must not be negative"))
+		}
+		if result.ExpiryGraceWindow < 0 {
+			panic(errors.New("expiry_grace_window must not be negative"))
+		}
+
+		if err := validateActiveAuthenticationMode(result.ActiveAuthentication); err != nil {
+			panic(errors.Wrap(err, "invalid active_authentication"))
+		}
+		// the config keys may be lowercased, so are the countries
+		countries := make(map[string]string, len(result.ActiveAuthenticationCountries))
+		for country, mode := range result.ActiveAuthenticationCountries {
+			if err := validateActiveAuthenticationMode(mode); err != nil {
+				panic(errors.Wrap(err, "invalid active_authentication_countries "+country))
 			}
+			countries[strings.ToLower(country)] = mode
+		}
+		result.ActiveAuthenticationCountries = countries
 
-			verificationKeys[algo] = verificationKey
+		return &result
+	}).(*VerifierConfig)
+}
+
+// ActiveAuthenticationMode returns the active authentication mode of the document signer country.
+func (c *VerifierConfig) ActiveAuthenticationMode(country string) string {
+	if mode, ok := c.ActiveAuthenticationCountries[strings.ToLower(country)]; ok {
+		return mode
+	}
+
+	return c.ActiveAuthentication
+}
+
+// ReadFiles reads the verification keys and trust anchors, it is called on every reload
+// so that the files can be replaced without restarting the service.
+func (c *VerifierConfig) ReadFiles() (*VerifierFiles, error) {
+	files := VerifierFiles{
+		VerificationKeys: make(map[string][]byte),
+		MasterLists:      make(map[string][]byte),
+	}
+
+	for _, circuit := range c.AllCircuits() {
+		verificationKey, err := os.ReadFile(circuit.VerificationKeyPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read verification key")
 		}
 
-		masterCerts, err := os.ReadFile(newCfg.MasterCertsPath)
+		files.VerificationKeys[circuit.ID] = verificationKey
+	}
+
+	if c.MasterCertsPath != "" {
+		masterCerts, err := os.ReadFile(c.MasterCertsPath)
 		if err != nil {
-			panic(err)
+			return nil, errors.Wrap(err, "failed to read master certs")
 		}
 
-		return &VerifierConfig{
-			VerificationKeys:    verificationKeys,
-			MasterCerts:         masterCerts,
-			AllowedAge:          newCfg.AllowedAge,
-			RegistrationTimeout: newCfg.RegistrationTimeout,
+		files.MasterCerts = masterCerts
+	}
+
+	for _, path := range c.MasterListsPaths {
+		masterList, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read master list")
 		}
-	}).(*VerifierConfig)
+
+		files.MasterLists[path] = masterList
+	}
+
+	if c.MasterListAnchorsPath != "" {
+		masterListAnchors, err := os.ReadFile(c.MasterListAnchorsPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read master list anchors")
+		}
+
+		files.MasterListAnchors = masterListAnchors
+	}
+
+	return &files, nil
+}
+
+// Paths returns the cleaned paths of all the files ReadFiles reads.
+func (c *VerifierConfig) Paths() []string {
+	circuits := c.AllCircuits()
+
+	paths := make([]string, 0, len(circuits)+len(c.MasterListsPaths)+2)
+	for _, circuit := range circuits {
+		paths = append(paths, filepath.Clean(circuit.VerificationKeyPath))
+	}
+	for _, path := range c.MasterListsPaths {
+		paths = append(paths, filepath.Clean(path))
+	}
+	if c.MasterCertsPath != "" {
+		paths = append(paths, filepath.Clean(c.MasterCertsPath))
+	}
+	if c.MasterListAnchorsPath != "" {
+		paths = append(paths, filepath.Clean(c.MasterListAnchorsPath))
+	}
+
+	return paths
+}
+
+// AllCircuits returns the circuits along with the legacy verification_keys_paths ones,
+// the latter are identified by their algorithm keys and are default for them.
+func (c *VerifierConfig) AllCircuits() []CircuitConfig {
+	circuits := make([]CircuitConfig, 0, len(c.VerificationKeysPaths)+len(c.Circuits))
+	for algo, path := range c.VerificationKeysPaths {
+		circuits = append(circuits, CircuitConfig{
+			ID:                  algo,
+			Algorithm:           algo,
+			VerificationKeyPath: path,
+			Default:             true,
+		})
+	}
+
+	return append(circuits, c.Circuits...)
 }
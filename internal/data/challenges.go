@@ -0,0 +1,35 @@
+package data
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type ChallengeQ interface {
+	New() ChallengeQ
+	Insert(value Challenge) error
+	FilterBy(column string, value any) ChallengeQ
+	// FilterActive selects the challenges not expired at now
+	FilterActive(now time.Time) ChallengeQ
+	Get() (*Challenge, error)
+	Count() (int, error)
+	ForUpdate() ChallengeQ
+	DeleteByNonce(nonce string) error
+	DeleteExpired(now time.Time) error
+}
+
+// Challenge is the registration nonce issued to the user, it is consumed by the
+// registration that commits to it.
+type Challenge struct {
+	// Nonce is the decimal BN254 field element
+	Nonce       string         `db:"nonce"        structs:"nonce"`
+	UserDID     string         `db:"user_did"     structs:"user_did"`
+	UserAddress common.Address `db:"user_address" structs:"user_address"`
+	ExpiresAt   time.Time      `db:"expires_at"   structs:"expires_at"`
+	CreatedAt   time.Time      `db:"created_at"   structs:"-"`
+	// CATerminalCurve and CATerminalKey are the chip authentication terminal ephemeral key
+	// curve name and hex-encoded private key, nil unless the user asked for the key
+	CATerminalCurve *string `db:"ca_terminal_curve" structs:"ca_terminal_curve"`
+	CATerminalKey   *string `db:"ca_terminal_key"   structs:"ca_terminal_key"`
+}
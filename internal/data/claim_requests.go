@@ -0,0 +1,69 @@
+package data
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+)
+
+// Claim request statuses
+const (
+	ClaimRequestPending = "pending"
+	ClaimRequestIssued  = "issued"
+	ClaimRequestFailed  = "failed"
+)
+
+type ClaimRequestQ interface {
+	New() ClaimRequestQ
+	Insert(value ClaimRequest) error
+	Update(value ClaimRequest) error
+	FilterBy(column string, value any) ClaimRequestQ
+	// FilterDue selects the oldest pending claim request due at now, whose document has
+	// no claim request leased
+	FilterDue(now time.Time) ClaimRequestQ
+	// FilterLeased selects the pending claim requests leased at now
+	FilterLeased(now time.Time) ClaimRequestQ
+	// FilterUnrecorded selects the claim requests with the claim issued, but not recorded
+	FilterUnrecorded() ClaimRequestQ
+	Get() (*ClaimRequest, error)
+	Select() ([]ClaimRequest, error)
+	// ForUpdateSkipLocked skips the claim requests locked by the other workers
+	ForUpdateSkipLocked() ClaimRequestQ
+	// LockDocument takes the transaction lock on the claim requests of the document
+	LockDocument(documentHash string) error
+}
+
+// ClaimRequest is the outbox entry of the accepted registration, the worker issues its
+// claim after the registration is committed.
+type ClaimRequest struct {
+	ID               uuid.UUID      `db:"id"                structs:"id"`
+	Status           string         `db:"status"            structs:"status"`
+	UserID           uuid.UUID      `db:"user_id"           structs:"user_id"`
+	UserDID          string         `db:"user_did"          structs:"user_did"`
+	UserAddress      common.Address `db:"user_address"      structs:"user_address"`
+	DocumentHash     string         `db:"document_hash"     structs:"document_hash"`
+	IssuingAuthority int64          `db:"issuing_authority" structs:"issuing_authority"`
+	IsAdult          bool           `db:"is_adult"          structs:"is_adult"`
+	Expiration       *time.Time     `db:"expiration"        structs:"expiration"`
+	DG2Hash          []byte         `db:"dg2_hash"          structs:"dg2_hash"`
+	Policy           string         `db:"policy"            structs:"policy"`
+	DSSerial         string         `db:"ds_serial"         structs:"ds_serial"`
+	DSCountry        string         `db:"ds_country"        structs:"ds_country"`
+
+	ActiveAuthentication bool `db:"active_authentication" structs:"active_authentication"`
+	ChipAuthentication   bool `db:"chip_authentication"   structs:"chip_authentication"`
+
+	// ClaimID is set as soon as the issuer has issued the claim, so it is not issued twice
+	ClaimID   *uuid.UUID `db:"claim_id"   structs:"claim_id"`
+	IssuerDID *string    `db:"issuer_did" structs:"issuer_did"`
+
+	Attempts      int       `db:"attempts"        structs:"attempts"`
+	NextAttemptAt time.Time `db:"next_attempt_at" structs:"next_attempt_at"`
+	// LeasedUntil is set while a worker issues the claim, no other claim request of the
+	// document is leased meanwhile
+	LeasedUntil *time.Time `db:"leased_until" structs:"leased_until"`
+	LastError   *string    `db:"last_error"   structs:"last_error"`
+	CreatedAt   time.Time  `db:"created_at"   structs:"-"`
+	UpdatedAt   time.Time  `db:"updated_at"   structs:"updated_at"`
+}
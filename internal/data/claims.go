@@ -5,14 +5,28 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+// Claim statuses recorded by the service, the credential status of the issuer may differ
+// if the credential is revoked on the issuer directly
+const (
+	ClaimActive = "active"
+	// ClaimSuperseded is the claim revoked as its document is registered again
+	ClaimSuperseded = "superseded"
+	// ClaimRevoked is the claim revoked by the operator
+	ClaimRevoked = "revoked"
 )
 
 type ClaimQ interface {
 	New() ClaimQ
 	Insert(value Claim) error
+	Update(value Claim) error
 	FilterBy(column string, value any) ClaimQ
 	Get() (*Claim, error)
 	Select() ([]Claim, error)
+	// Page selects the page of the claims ordered by the creation time
+	Page(params *pgdb.OffsetPageParams) ClaimQ
 	DeleteByID(id uuid.UUID) error
 	ForUpdate() ClaimQ
 	ResetFilter() ClaimQ
@@ -26,4 +40,18 @@ type Claim struct {
 	UserAddress  common.Address `db:"user_address"  structs:"user_address"`
 	DocumentHash string         `db:"document_hash" structs:"document_hash"`
 	CreatedAt    time.Time      `db:"created_at"    structs:"-"`
+	// ActiveAuthentication is whether the chip proved it is genuine by active authentication
+	ActiveAuthentication bool `db:"active_authentication" structs:"active_authentication"`
+	// ChipAuthentication is whether the chip proved it is genuine by chip authentication
+	ChipAuthentication bool `db:"chip_authentication" structs:"chip_authentication"`
+	// Policy is the eligibility policy the registration satisfied
+	Policy string `db:"policy" structs:"policy"`
+	// DSSerial is the lowercase hex serial number of the document signer certificate
+	DSSerial  string `db:"ds_serial"  structs:"ds_serial"`
+	DSCountry string `db:"ds_country" structs:"ds_country"`
+	Status    string `db:"status"     structs:"status"`
+	// RevocationNonce and ExpiresAt are the credential ones recorded at the issuance, nil
+	// for the claims recorded before the 009_claims_status migration
+	RevocationNonce *int64     `db:"revocation_nonce" structs:"revocation_nonce"`
+	ExpiresAt       *time.Time `db:"expires_at"       structs:"expires_at"`
 }
@@ -0,0 +1,87 @@
+package datatest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+)
+
+type challengesQ struct {
+	db      *DB
+	filters filters[data.Challenge]
+}
+
+func (q *challengesQ) New() data.ChallengeQ {
+	return &challengesQ{db: q.db}
+}
+
+func (q *challengesQ) Insert(value data.Challenge) error {
+	q.db.mu.Lock()
+	defer q.db.mu.Unlock()
+
+	if _, ok := q.db.Challenges[value.Nonce]; ok {
+		return fmt.Errorf("challenge %s already exists", value.Nonce)
+	}
+	if value.CreatedAt.IsZero() {
+		value.CreatedAt = time.Now().UTC()
+	}
+
+	q.db.Challenges[value.Nonce] = value
+	return nil
+}
+
+func (q *challengesQ) FilterBy(name string, value any) data.ChallengeQ {
+	q.filters = append(q.filters, column[data.Challenge](name, value))
+	return q
+}
+
+func (q *challengesQ) FilterActive(now time.Time) data.ChallengeQ {
+	q.filters = append(q.filters, func(challenge data.Challenge) bool {
+		return challenge.ExpiresAt.After(now)
+	})
+	return q
+}
+
+func (q *challengesQ) Get() (*data.Challenge, error) {
+	challenges := selectRows(q.db, func(db *DB) map[string]data.Challenge { return db.Challenges }, q.filters,
+		func(a, b data.Challenge) bool {
+			return a.CreatedAt.Before(b.CreatedAt)
+		})
+	if len(challenges) == 0 {
+		return nil, nil
+	}
+
+	return &challenges[0], nil
+}
+
+func (q *challengesQ) Count() (int, error) {
+	challenges := selectRows(q.db, func(db *DB) map[string]data.Challenge { return db.Challenges }, q.filters,
+		func(a, b data.Challenge) bool { return false })
+
+	return len(challenges), nil
+}
+
+func (q *challengesQ) ForUpdate() data.ChallengeQ {
+	return q
+}
+
+func (q *challengesQ) DeleteByNonce(nonce string) error {
+	q.db.mu.Lock()
+	defer q.db.mu.Unlock()
+
+	delete(q.db.Challenges, nonce)
+	return nil
+}
+
+func (q *challengesQ) DeleteExpired(now time.Time) error {
+	q.db.mu.Lock()
+	defer q.db.mu.Unlock()
+
+	for nonce, challenge := range q.db.Challenges {
+		if !challenge.ExpiresAt.After(now) {
+			delete(q.db.Challenges, nonce)
+		}
+	}
+	return nil
+}
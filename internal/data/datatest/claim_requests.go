@@ -0,0 +1,126 @@
+package datatest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+)
+
+type claimRequestsQ struct {
+	db      *DB
+	filters filters[data.ClaimRequest]
+	// due is set by FilterDue, the oldest due claim request is selected then
+	due *time.Time
+}
+
+func (q *claimRequestsQ) New() data.ClaimRequestQ {
+	return &claimRequestsQ{db: q.db}
+}
+
+func (q *claimRequestsQ) Insert(value data.ClaimRequest) error {
+	q.db.mu.Lock()
+	defer q.db.mu.Unlock()
+
+	if _, ok := q.db.ClaimRequests[value.ID]; ok {
+		return fmt.Errorf("claim request %s already exists", value.ID)
+	}
+	if value.CreatedAt.IsZero() {
+		value.CreatedAt = time.Now().UTC()
+	}
+
+	q.db.ClaimRequests[value.ID] = value
+	return nil
+}
+
+func (q *claimRequestsQ) Update(value data.ClaimRequest) error {
+	q.db.mu.Lock()
+	defer q.db.mu.Unlock()
+
+	if _, ok := q.db.ClaimRequests[value.ID]; !ok {
+		return nil
+	}
+
+	q.db.ClaimRequests[value.ID] = value
+	return nil
+}
+
+func (q *claimRequestsQ) FilterBy(name string, value any) data.ClaimRequestQ {
+	q.filters = append(q.filters, column[data.ClaimRequest](name, value))
+	return q
+}
+
+func (q *claimRequestsQ) FilterDue(now time.Time) data.ClaimRequestQ {
+	q.due = &now
+	q.filters = append(q.filters, func(request data.ClaimRequest) bool {
+		return request.Status == data.ClaimRequestPending && !request.NextAttemptAt.After(now)
+	})
+	return q
+}
+
+func (q *claimRequestsQ) FilterLeased(now time.Time) data.ClaimRequestQ {
+	q.filters = append(q.filters, leased(now))
+	return q
+}
+
+func (q *claimRequestsQ) FilterUnrecorded() data.ClaimRequestQ {
+	q.filters = append(q.filters, func(request data.ClaimRequest) bool {
+		return request.ClaimID != nil && request.Status != data.ClaimRequestIssued
+	})
+	return q
+}
+
+func (q *claimRequestsQ) Get() (*data.ClaimRequest, error) {
+	requests, err := q.Select()
+	if err != nil || len(requests) == 0 {
+		return nil, err
+	}
+
+	return &requests[0], nil
+}
+
+func (q *claimRequestsQ) Select() ([]data.ClaimRequest, error) {
+	requests := selectRows(q.db, func(db *DB) map[uuid.UUID]data.ClaimRequest { return db.ClaimRequests }, q.filters,
+		func(a, b data.ClaimRequest) bool {
+			if q.due != nil {
+				return a.NextAttemptAt.Before(b.NextAttemptAt)
+			}
+			return a.CreatedAt.Before(b.CreatedAt)
+		})
+	if q.due == nil {
+		return requests, nil
+	}
+
+	// the claim requests of the leased documents are not due
+	leasedRequests := selectRows(q.db, func(db *DB) map[uuid.UUID]data.ClaimRequest { return db.ClaimRequests }, filters[data.ClaimRequest]{leased(*q.due)},
+		func(a, b data.ClaimRequest) bool { return false })
+	for _, request := range requests {
+		free := true
+		for _, leasedRequest := range leasedRequests {
+			if leasedRequest.DocumentHash == request.DocumentHash {
+				free = false
+			}
+		}
+		if free {
+			return []data.ClaimRequest{request}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (q *claimRequestsQ) ForUpdateSkipLocked() data.ClaimRequestQ {
+	return q
+}
+
+func (q *claimRequestsQ) LockDocument(string) error {
+	return nil
+}
+
+func leased(now time.Time) func(data.ClaimRequest) bool {
+	return func(request data.ClaimRequest) bool {
+		return request.Status == data.ClaimRequestPending && request.LeasedUntil != nil && request.LeasedUntil.After(now)
+	}
+}
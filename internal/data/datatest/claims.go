@@ -0,0 +1,114 @@
+package datatest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+)
+
+type claimsQ struct {
+	db      *DB
+	filters filters[data.Claim]
+	page    *pgdb.OffsetPageParams
+}
+
+func (q *claimsQ) New() data.ClaimQ {
+	return &claimsQ{db: q.db}
+}
+
+func (q *claimsQ) Insert(value data.Claim) error {
+	q.db.mu.Lock()
+	defer q.db.mu.Unlock()
+
+	if _, ok := q.db.Claims[value.ID]; ok {
+		return fmt.Errorf("claim %s already exists", value.ID)
+	}
+	if value.CreatedAt.IsZero() {
+		value.CreatedAt = time.Now().UTC()
+	}
+
+	q.db.Claims[value.ID] = value
+	return nil
+}
+
+func (q *claimsQ) Update(value data.Claim) error {
+	q.db.mu.Lock()
+	defer q.db.mu.Unlock()
+
+	if _, ok := q.db.Claims[value.ID]; !ok {
+		return nil
+	}
+
+	q.db.Claims[value.ID] = value
+	return nil
+}
+
+func (q *claimsQ) FilterBy(name string, value any) data.ClaimQ {
+	q.filters = append(q.filters, column[data.Claim](name, value))
+	return q
+}
+
+func (q *claimsQ) Get() (*data.Claim, error) {
+	claims, err := q.Select()
+	if err != nil || len(claims) == 0 {
+		return nil, err
+	}
+
+	return &claims[0], nil
+}
+
+func (q *claimsQ) Select() ([]data.Claim, error) {
+	claims := selectRows(q.db, func(db *DB) map[uuid.UUID]data.Claim { return db.Claims }, q.filters, func(a, b data.Claim) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	if q.page == nil {
+		return claims, nil
+	}
+
+	if q.page.Order != pgdb.OrderTypeAsc {
+		for i, j := 0, len(claims)-1; i < j; i, j = i+1, j-1 {
+			claims[i], claims[j] = claims[j], claims[i]
+		}
+	}
+
+	offset := min(q.page.Limit*q.page.PageNumber, uint64(len(claims)))
+
+	return claims[offset:min(offset+q.page.Limit, uint64(len(claims)))], nil
+}
+
+// Page fills the page defaults the same way pgdb.OffsetPageParams.ApplyTo does.
+func (q *claimsQ) Page(params *pgdb.OffsetPageParams) data.ClaimQ {
+	if params.Limit == 0 {
+		params.Limit = 15
+	}
+	if params.Order == "" {
+		params.Order = pgdb.OrderTypeDesc
+	}
+
+	q.page = params
+	return q
+}
+
+func (q *claimsQ) DeleteByID(id uuid.UUID) error {
+	q.db.mu.Lock()
+	defer q.db.mu.Unlock()
+
+	delete(q.db.Claims, id)
+	return nil
+}
+
+func (q *claimsQ) ForUpdate() data.ClaimQ {
+	return q
+}
+
+func (q *claimsQ) ResetFilter() data.ClaimQ {
+	q.filters = nil
+	return q
+}
@@ -0,0 +1,172 @@
+// Package datatest provides the in-memory MasterQ for the tests of the packages using the data layer.
+package datatest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+)
+
+// DB is the in-memory store behind the MasterQ, the tests may inspect and modify its
+// tables directly. The transactions are rolled back on error, but are not isolated.
+type DB struct {
+	mu sync.Mutex
+
+	Claims          map[uuid.UUID]data.Claim
+	ProofNullifiers map[string]data.ProofNullifier
+	Challenges      map[string]data.Challenge
+	ClaimRequests   map[uuid.UUID]data.ClaimRequest
+	Revocations     map[uuid.UUID]data.Revocation
+}
+
+func New() *DB {
+	return &DB{
+		Claims:          make(map[uuid.UUID]data.Claim),
+		ProofNullifiers: make(map[string]data.ProofNullifier),
+		Challenges:      make(map[string]data.Challenge),
+		ClaimRequests:   make(map[uuid.UUID]data.ClaimRequest),
+		Revocations:     make(map[uuid.UUID]data.Revocation),
+	}
+}
+
+// MasterQ returns the MasterQ on the store.
+func (db *DB) MasterQ() data.MasterQ {
+	return &masterQ{db: db}
+}
+
+type masterQ struct {
+	db *DB
+}
+
+func (m *masterQ) New() data.MasterQ {
+	return &masterQ{db: m.db}
+}
+
+func (m *masterQ) Claim() data.ClaimQ {
+	return &claimsQ{db: m.db}
+}
+
+func (m *masterQ) ProofNullifier() data.ProofNullifierQ {
+	return &proofNullifiersQ{db: m.db}
+}
+
+func (m *masterQ) Challenge() data.ChallengeQ {
+	return &challengesQ{db: m.db}
+}
+
+func (m *masterQ) ClaimRequest() data.ClaimRequestQ {
+	return &claimRequestsQ{db: m.db}
+}
+
+func (m *masterQ) Revocation() data.RevocationQ {
+	return &revocationsQ{db: m.db}
+}
+
+func (m *masterQ) Transaction(fn func(db data.MasterQ) error) error {
+	snapshot := m.db.snapshot()
+	if err := fn(m); err != nil {
+		m.db.restore(snapshot)
+		return err
+	}
+
+	return nil
+}
+
+func (db *DB) snapshot() *DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return &DB{
+		Claims:          clone(db.Claims),
+		ProofNullifiers: clone(db.ProofNullifiers),
+		Challenges:      clone(db.Challenges),
+		ClaimRequests:   clone(db.ClaimRequests),
+		Revocations:     clone(db.Revocations),
+	}
+}
+
+func (db *DB) restore(snapshot *DB) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.Claims = snapshot.Claims
+	db.ProofNullifiers = snapshot.ProofNullifiers
+	db.Challenges = snapshot.Challenges
+	db.ClaimRequests = snapshot.ClaimRequests
+	db.Revocations = snapshot.Revocations
+}
+
+func clone[K comparable, V any](table map[K]V) map[K]V {
+	result := make(map[K]V, len(table))
+	for key, value := range table {
+		result[key] = value
+	}
+
+	return result
+}
+
+// filters are the conditions the selected rows match.
+type filters[T any] []func(T) bool
+
+func (f filters[T]) match(row T) bool {
+	for _, filter := range f {
+		if !filter(row) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// column returns the filter comparing the column to the value in their default format,
+// so that e.g. the UUID column may be compared to the string.
+func column[T any](name string, value any) func(T) bool {
+	want := fmt.Sprint(indirect(value))
+
+	return func(row T) bool {
+		rowValue := reflect.ValueOf(row)
+		for i := 0; i < rowValue.NumField(); i++ {
+			if rowValue.Type().Field(i).Tag.Get("db") == name {
+				return fmt.Sprint(indirect(rowValue.Field(i).Interface())) == want
+			}
+		}
+
+		panic(fmt.Sprintf("unknown column %s", name))
+	}
+}
+
+func indirect(value any) any {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		return rv.Elem().Interface()
+	}
+
+	return value
+}
+
+// selectRows returns the matching rows ordered by less.
+func selectRows[K comparable, T any](db *DB, table func(*DB) map[K]T, f filters[T], less func(a, b T) bool) []T {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var result []T
+	for _, row := range table(db) {
+		if f.match(row) {
+			result = append(result, row)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return less(result[i], result[j])
+	})
+
+	return result
+}
@@ -0,0 +1,48 @@
+package datatest
+
+import (
+	"time"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+)
+
+type proofNullifiersQ struct {
+	db      *DB
+	filters filters[data.ProofNullifier]
+}
+
+func (q *proofNullifiersQ) New() data.ProofNullifierQ {
+	return &proofNullifiersQ{db: q.db}
+}
+
+func (q *proofNullifiersQ) Insert(value data.ProofNullifier) error {
+	q.db.mu.Lock()
+	defer q.db.mu.Unlock()
+
+	if _, ok := q.db.ProofNullifiers[string(value.Hash)]; ok {
+		return data.ErrProofNullifierExists
+	}
+	if value.CreatedAt.IsZero() {
+		value.CreatedAt = time.Now().UTC()
+	}
+
+	q.db.ProofNullifiers[string(value.Hash)] = value
+	return nil
+}
+
+func (q *proofNullifiersQ) FilterBy(name string, value any) data.ProofNullifierQ {
+	q.filters = append(q.filters, column[data.ProofNullifier](name, value))
+	return q
+}
+
+func (q *proofNullifiersQ) Get() (*data.ProofNullifier, error) {
+	nullifiers := selectRows(q.db, func(db *DB) map[string]data.ProofNullifier { return db.ProofNullifiers }, q.filters,
+		func(a, b data.ProofNullifier) bool {
+			return a.CreatedAt.Before(b.CreatedAt)
+		})
+	if len(nullifiers) == 0 {
+		return nil, nil
+	}
+
+	return &nullifiers[0], nil
+}
@@ -0,0 +1,59 @@
+package datatest
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+)
+
+type revocationsQ struct {
+	db      *DB
+	filters filters[data.Revocation]
+}
+
+func (q *revocationsQ) New() data.RevocationQ {
+	return &revocationsQ{db: q.db}
+}
+
+func (q *revocationsQ) Insert(value data.Revocation) error {
+	q.db.mu.Lock()
+	defer q.db.mu.Unlock()
+
+	if _, ok := q.db.Revocations[value.ID]; ok {
+		return fmt.Errorf("revocation %s already exists", value.ID)
+	}
+
+	q.db.Revocations[value.ID] = value
+	return nil
+}
+
+func (q *revocationsQ) Update(value data.Revocation) error {
+	q.db.mu.Lock()
+	defer q.db.mu.Unlock()
+
+	if _, ok := q.db.Revocations[value.ID]; !ok {
+		return nil
+	}
+
+	q.db.Revocations[value.ID] = value
+	return nil
+}
+
+func (q *revocationsQ) FilterBy(name string, value any) data.RevocationQ {
+	q.filters = append(q.filters, column[data.Revocation](name, value))
+	return q
+}
+
+func (q *revocationsQ) Get() (*data.Revocation, error) {
+	revocations := selectRows(q.db, func(db *DB) map[uuid.UUID]data.Revocation { return db.Revocations }, q.filters,
+		func(a, b data.Revocation) bool {
+			return a.CreatedAt.Before(b.CreatedAt)
+		})
+	if len(revocations) == 0 {
+		return nil, nil
+	}
+
+	return &revocations[0], nil
+}
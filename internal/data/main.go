@@ -4,6 +4,10 @@ type MasterQ interface {
 	New() MasterQ
 
 	Claim() ClaimQ
+	ProofNullifier() ProofNullifierQ
+	Challenge() ChallengeQ
+	ClaimRequest() ClaimRequestQ
+	Revocation() RevocationQ
 
 	Transaction(fn func(db MasterQ) error) error
 }
@@ -0,0 +1,73 @@
+package pg
+
+import (
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const challengesTableName = "challenges"
+
+func NewChallengesQ(db *pgdb.DB) data.ChallengeQ {
+	return &challengesQ{
+		db:  db,
+		sql: sq.Select("*").From(challengesTableName),
+	}
+}
+
+type challengesQ struct {
+	db  *pgdb.DB
+	sql sq.SelectBuilder
+}
+
+func (q *challengesQ) New() data.ChallengeQ {
+	return NewChallengesQ(q.db.Clone())
+}
+
+func (q *challengesQ) Insert(value data.Challenge) error {
+	clauses := structs.Map(value)
+	stmt := sq.Insert(challengesTableName).SetMap(clauses)
+	return q.db.Exec(stmt)
+}
+
+func (q *challengesQ) FilterBy(column string, value any) data.ChallengeQ {
+	q.sql = q.sql.Where(sq.Eq{column: value})
+	return q
+}
+
+func (q *challengesQ) FilterActive(now time.Time) data.ChallengeQ {
+	q.sql = q.sql.Where(sq.Gt{"expires_at": now})
+	return q
+}
+
+func (q *challengesQ) Get() (*data.Challenge, error) {
+	var result data.Challenge
+	err := q.db.Get(&result, q.sql)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &result, err
+}
+
+func (q *challengesQ) Count() (int, error) {
+	var result int
+	err := q.db.Get(&result, q.sql.RemoveColumns().Columns("count(*)"))
+	return result, err
+}
+
+func (q *challengesQ) ForUpdate() data.ChallengeQ {
+	q.sql = q.sql.Suffix("FOR UPDATE")
+	return q
+}
+
+func (q *challengesQ) DeleteByNonce(nonce string) error {
+	return q.db.Exec(sq.Delete(challengesTableName).Where(sq.Eq{"nonce": nonce}))
+}
+
+func (q *challengesQ) DeleteExpired(now time.Time) error {
+	return q.db.Exec(sq.Delete(challengesTableName).Where(sq.LtOrEq{"expires_at": now}))
+}
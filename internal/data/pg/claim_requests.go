@@ -0,0 +1,99 @@
+package pg
+
+import (
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const claimRequestsTableName = "claim_requests"
+
+func NewClaimRequestsQ(db *pgdb.DB) data.ClaimRequestQ {
+	return &claimRequestsQ{
+		db:  db,
+		sql: sq.Select("*").From(claimRequestsTableName),
+	}
+}
+
+type claimRequestsQ struct {
+	db  *pgdb.DB
+	sql sq.SelectBuilder
+}
+
+func (q *claimRequestsQ) New() data.ClaimRequestQ {
+	return NewClaimRequestsQ(q.db.Clone())
+}
+
+func (q *claimRequestsQ) Insert(value data.ClaimRequest) error {
+	clauses := structs.Map(value)
+	stmt := sq.Insert(claimRequestsTableName).SetMap(clauses)
+	return q.db.Exec(stmt)
+}
+
+func (q *claimRequestsQ) Update(value data.ClaimRequest) error {
+	clauses := structs.Map(value)
+	delete(clauses, "id")
+	stmt := sq.Update(claimRequestsTableName).SetMap(clauses).Where(sq.Eq{"id": value.ID})
+	return q.db.Exec(stmt)
+}
+
+func (q *claimRequestsQ) FilterBy(column string, value any) data.ClaimRequestQ {
+	q.sql = q.sql.Where(sq.Eq{column: value})
+	return q
+}
+
+func (q *claimRequestsQ) FilterDue(now time.Time) data.ClaimRequestQ {
+	q.sql = q.sql.
+		Where(sq.Eq{"status": data.ClaimRequestPending}).
+		Where(sq.LtOrEq{"next_attempt_at": now}).
+		Where(sq.Expr(
+			"not exists (select 1 from "+claimRequestsTableName+" leased where leased.document_hash = "+
+				claimRequestsTableName+".document_hash and leased.status = ? and leased.leased_until > ?)",
+			data.ClaimRequestPending, now,
+		)).
+		OrderBy("next_attempt_at").
+		Limit(1)
+	return q
+}
+
+func (q *claimRequestsQ) FilterLeased(now time.Time) data.ClaimRequestQ {
+	q.sql = q.sql.
+		Where(sq.Eq{"status": data.ClaimRequestPending}).
+		Where(sq.Gt{"leased_until": now})
+	return q
+}
+
+func (q *claimRequestsQ) FilterUnrecorded() data.ClaimRequestQ {
+	q.sql = q.sql.
+		Where(sq.NotEq{"claim_id": nil}).
+		Where(sq.NotEq{"status": data.ClaimRequestIssued})
+	return q
+}
+
+func (q *claimRequestsQ) Get() (*data.ClaimRequest, error) {
+	var result data.ClaimRequest
+	err := q.db.Get(&result, q.sql)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &result, err
+}
+
+func (q *claimRequestsQ) Select() ([]data.ClaimRequest, error) {
+	var result []data.ClaimRequest
+	err := q.db.Select(&result, q.sql)
+	return result, err
+}
+
+func (q *claimRequestsQ) ForUpdateSkipLocked() data.ClaimRequestQ {
+	q.sql = q.sql.Suffix("FOR UPDATE SKIP LOCKED")
+	return q
+}
+
+func (q *claimRequestsQ) LockDocument(documentHash string) error {
+	return q.db.Exec(sq.Expr("select pg_advisory_xact_lock(hashtext(?), hashtext(?))", claimRequestsTableName, documentHash))
+}
@@ -41,6 +41,12 @@ func (q *claimsQ) Insert(value data.Claim) error {
 	return err
 }
 
+func (q *claimsQ) Update(value data.Claim) error {
+	clauses := structs.Map(value)
+	delete(clauses, "id")
+	return q.db.Exec(q.upd.SetMap(clauses).Where(sq.Eq{"id": value.ID}))
+}
+
 func (q *claimsQ) FilterBy(column string, value any) data.ClaimQ {
 	q.sql = q.sql.Where(sq.Eq{column: value})
 	return q
@@ -61,6 +67,11 @@ func (q *claimsQ) Select() ([]data.Claim, error) {
 	return result, err
 }
 
+func (q *claimsQ) Page(params *pgdb.OffsetPageParams) data.ClaimQ {
+	q.sql = params.ApplyTo(q.sql, "created_at", "id")
+	return q
+}
+
 func (q *claimsQ) DeleteByID(id uuid.UUID) error {
 	if err := q.db.Exec(sq.Delete(claimsTableName).Where(sq.Eq{"id": id})); err != nil {
 		return err
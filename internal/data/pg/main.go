@@ -28,3 +28,19 @@ func (m *masterQ) Transaction(fn func(q data.MasterQ) error) error {
 func (m *masterQ) Claim() data.ClaimQ {
 	return NewClaimsQ(m.db)
 }
+
+func (m *masterQ) ProofNullifier() data.ProofNullifierQ {
+	return NewProofNullifiersQ(m.db)
+}
+
+func (m *masterQ) Challenge() data.ChallengeQ {
+	return NewChallengesQ(m.db)
+}
+
+func (m *masterQ) ClaimRequest() data.ClaimRequestQ {
+	return NewClaimRequestsQ(m.db)
+}
+
+func (m *masterQ) Revocation() data.RevocationQ {
+	return NewRevocationsQ(m.db)
+}
@@ -0,0 +1,55 @@
+package pg
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const (
+	proofNullifiersTableName = "proof_nullifiers"
+	proofNullifiersPKey      = "proof_nullifiers_pkey"
+)
+
+func NewProofNullifiersQ(db *pgdb.DB) data.ProofNullifierQ {
+	return &proofNullifiersQ{
+		db:  db,
+		sql: sq.Select("*").From(proofNullifiersTableName),
+	}
+}
+
+type proofNullifiersQ struct {
+	db  *pgdb.DB
+	sql sq.SelectBuilder
+}
+
+func (q *proofNullifiersQ) New() data.ProofNullifierQ {
+	return NewProofNullifiersQ(q.db.Clone())
+}
+
+func (q *proofNullifiersQ) Insert(value data.ProofNullifier) error {
+	clauses := structs.Map(value)
+	stmt := sq.Insert(proofNullifiersTableName).SetMap(clauses)
+	err := q.db.Exec(stmt)
+	if pgdb.IsConstraintErr(err, proofNullifiersPKey) {
+		return data.ErrProofNullifierExists
+	}
+	return err
+}
+
+func (q *proofNullifiersQ) FilterBy(column string, value any) data.ProofNullifierQ {
+	q.sql = q.sql.Where(sq.Eq{column: value})
+	return q
+}
+
+func (q *proofNullifiersQ) Get() (*data.ProofNullifier, error) {
+	var result data.ProofNullifier
+	err := q.db.Get(&result, q.sql)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &result, err
+}
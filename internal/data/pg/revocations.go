@@ -0,0 +1,55 @@
+package pg
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const revocationsTableName = "revocations"
+
+func NewRevocationsQ(db *pgdb.DB) data.RevocationQ {
+	return &revocationsQ{
+		db:  db,
+		sql: sq.Select("*").From(revocationsTableName),
+	}
+}
+
+type revocationsQ struct {
+	db  *pgdb.DB
+	sql sq.SelectBuilder
+}
+
+func (q *revocationsQ) New() data.RevocationQ {
+	return NewRevocationsQ(q.db.Clone())
+}
+
+func (q *revocationsQ) Insert(value data.Revocation) error {
+	clauses := structs.Map(value)
+	stmt := sq.Insert(revocationsTableName).SetMap(clauses)
+	return q.db.Exec(stmt)
+}
+
+func (q *revocationsQ) Update(value data.Revocation) error {
+	clauses := structs.Map(value)
+	delete(clauses, "id")
+	stmt := sq.Update(revocationsTableName).SetMap(clauses).Where(sq.Eq{"id": value.ID})
+	return q.db.Exec(stmt)
+}
+
+func (q *revocationsQ) FilterBy(column string, value any) data.RevocationQ {
+	q.sql = q.sql.Where(sq.Eq{column: value})
+	return q
+}
+
+func (q *revocationsQ) Get() (*data.Revocation, error) {
+	var result data.Revocation
+	err := q.db.Get(&result, q.sql)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &result, err
+}
@@ -0,0 +1,25 @@
+package data
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// ErrProofNullifierExists is returned on the insert of the already proven statement.
+var ErrProofNullifierExists = errors.New("proof nullifier already exists")
+
+type ProofNullifierQ interface {
+	New() ProofNullifierQ
+	Insert(value ProofNullifier) error
+	FilterBy(column string, value any) ProofNullifierQ
+	Get() (*ProofNullifier, error)
+}
+
+// ProofNullifier is the hash of the proven statement, the statement is never accepted twice
+// whatever proof of it is submitted.
+type ProofNullifier struct {
+	Hash         []byte    `db:"hash"          structs:"hash"`
+	DocumentHash string    `db:"document_hash" structs:"document_hash"`
+	CreatedAt    time.Time `db:"created_at"    structs:"-"`
+}
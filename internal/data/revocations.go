@@ -0,0 +1,39 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Revocation statuses
+const (
+	// RevocationPending is recorded before the issuer is asked to revoke the credential
+	RevocationPending = "pending"
+	RevocationDone    = "done"
+)
+
+type RevocationQ interface {
+	New() RevocationQ
+	Insert(value Revocation) error
+	Update(value Revocation) error
+	FilterBy(column string, value any) RevocationQ
+	Get() (*Revocation, error)
+}
+
+// Revocation is the audit record of the claim revoked by the operator. The claim request
+// cancelled before its claim is issued has no claim ID and revocation nonce.
+type Revocation struct {
+	ID              uuid.UUID  `db:"id"               structs:"id"`
+	ClaimID         *uuid.UUID `db:"claim_id"         structs:"claim_id"`
+	ClaimRequestID  *uuid.UUID `db:"claim_request_id" structs:"claim_request_id"`
+	UserID          uuid.UUID  `db:"user_id"          structs:"user_id"`
+	DocumentHash    string     `db:"document_hash"    structs:"document_hash"`
+	RevocationNonce *int64     `db:"revocation_nonce" structs:"revocation_nonce"`
+	Reason          string     `db:"reason"           structs:"reason"`
+	Operator        string     `db:"operator"         structs:"operator"`
+	// Selector is how the operator selected the claim, e.g. ds_serial=1a2b
+	Selector  string    `db:"selector"   structs:"selector"`
+	Status    string    `db:"status"     structs:"status"`
+	CreatedAt time.Time `db:"created_at" structs:"created_at"`
+}
@@ -0,0 +1,173 @@
+package activeauth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/certificates"
+)
+
+// ChallengeSize is the size of the challenge the chip signs, see ICAO 9303 part 11, section 6.1
+const ChallengeSize = 8
+
+var ErrInvalidSignature = errors.New("invalid active authentication signature")
+
+// iso9796Hashes are the hash functions of the ISO/IEC 9796-2 two-byte trailers
+var iso9796Hashes = map[byte]crypto.Hash{
+	0x33: crypto.SHA1,
+	0x34: crypto.SHA256,
+	0x35: crypto.SHA512,
+	0x36: crypto.SHA384,
+	0x38: crypto.SHA224,
+}
+
+var hashNames = map[string]crypto.Hash{
+	"sha1":   crypto.SHA1,
+	"sha224": crypto.SHA224,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+// Challenge derives the chip challenge from the registration challenge nonce: the
+// leading bytes of the SHA-256 of the nonce encoded as the 32-byte big-endian integer.
+func Challenge(nonce *big.Int) []byte {
+	encoded := make([]byte, 32)
+	digest := sha256.Sum256(nonce.FillBytes(encoded))
+	return digest[:ChallengeSize]
+}
+
+// ParseDG15 returns the active authentication public key of the DG15 file.
+func ParseDG15(dg15 []byte) (crypto.PublicKey, error) {
+	var file asn1.RawValue
+	rest, err := asn1.Unmarshal(dg15, &file)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal DG15")
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after DG15")
+	}
+	if file.Class != asn1.ClassApplication || file.Tag != 15 {
+		return nil, fmt.Errorf("expected DG15 tag, got class %d tag %d", file.Class, file.Tag)
+	}
+
+	publicKey, err := certificates.ParsePublicKey(file.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse DG15 public key")
+	}
+
+	return publicKey, nil
+}
+
+// HashByName returns the hash function by its name, e.g. SHA-256 or sha256.
+func HashByName(name string) (crypto.Hash, error) {
+	hash, ok := hashNames[strings.ReplaceAll(strings.ToLower(name), "-", "")]
+	if !ok {
+		return 0, fmt.Errorf("%s is not supported hash algorithm", name)
+	}
+
+	return hash, nil
+}
+
+// Verify checks the chip signature of the challenge. RSA signatures are ISO/IEC 9796-2
+// scheme 1 ones with the challenge as the non-recoverable part, ECDSA signatures are
+// plain r || s of the challenge hashed by the hash function, see BSI TR-03111.
+func Verify(publicKey crypto.PublicKey, challenge, signature []byte, hash crypto.Hash) error {
+	switch pubKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		return verifyISO9796(pubKey, challenge, signature)
+	case *ecdsa.PublicKey:
+		return verifyECDSA(pubKey, challenge, signature, hash)
+	default:
+		return fmt.Errorf("unsupported active authentication key %T", publicKey)
+	}
+}
+
+func verifyISO9796(publicKey *rsa.PublicKey, challenge, signature []byte) error {
+	s := new(big.Int).SetBytes(signature)
+	if s.Cmp(publicKey.N) >= 0 {
+		return ErrInvalidSignature
+	}
+
+	m := new(big.Int).Exp(s, big.NewInt(int64(publicKey.E)), publicKey.N)
+	block := m.FillBytes(make([]byte, publicKey.Size()))
+
+	// the header is 01 followed by the partial recovery bit, the challenge is not in the block
+	if block[0]&0xC0 != 0x40 || block[0]&0x20 == 0 {
+		return ErrInvalidSignature
+	}
+
+	hash, trailerLen := crypto.SHA1, 1
+	switch last := block[len(block)-1]; {
+	case last == 0xBC:
+	case last == 0xCC:
+		var ok bool
+		if hash, ok = iso9796Hashes[block[len(block)-2]]; !ok {
+			return fmt.Errorf("unsupported ISO/IEC 9796-2 hash identifier %x", block[len(block)-2])
+		}
+		trailerLen = 2
+	default:
+		return ErrInvalidSignature
+	}
+	if !hash.Available() {
+		return fmt.Errorf("hash function %s is not available", hash)
+	}
+
+	// the padding ends with the nibble 1010
+	messageStart := 0
+	for messageStart < len(block) && block[messageStart]&0x0F != 0x0A {
+		messageStart++
+	}
+	messageStart++
+
+	digestStart := len(block) - trailerLen - hash.Size()
+	if digestStart < messageStart {
+		return ErrInvalidSignature
+	}
+
+	h := hash.New()
+	h.Write(block[messageStart:digestStart])
+	h.Write(challenge)
+
+	if !bytes.Equal(h.Sum(nil), block[digestStart:len(block)-trailerLen]) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func verifyECDSA(publicKey *ecdsa.PublicKey, challenge, signature []byte, hash crypto.Hash) error {
+	if hash == 0 {
+		return errors.New("hash algorithm is required for ECDSA active authentication")
+	}
+	if !hash.Available() {
+		return fmt.Errorf("hash function %s is not available", hash)
+	}
+
+	orderLen := (publicKey.Curve.Params().N.BitLen() + 7) / 8
+	if len(signature) != 2*orderLen {
+		return ErrInvalidSignature
+	}
+
+	h := hash.New()
+	h.Write(challenge)
+
+	r := new(big.Int).SetBytes(signature[:orderLen])
+	s := new(big.Int).SetBytes(signature[orderLen:])
+	if !ecdsa.Verify(publicKey, h.Sum(nil), r, s) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package algorithms
+
+import (
+	"crypto"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// Full list of the OpenSSL signature algorithms and hash-functions is provided here:
+// https://www.openssl.org/docs/man1.1.1/man3/SSL_CTX_set1_sigalgs_list.html
+
+const (
+	SHA256withRSA    = "SHA256withRSA"
+	SHA384withRSA    = "SHA384withRSA"
+	SHA512withRSA    = "SHA512withRSA"
+	SHA256withRSAPSS = "SHA256withRSAPSS"
+	SHA384withRSAPSS = "SHA384withRSAPSS"
+	SHA512withRSAPSS = "SHA512withRSAPSS"
+	SHA1withECDSA    = "SHA1withECDSA"
+	SHA256withECDSA  = "SHA256withECDSA"
+	SHA384withECDSA  = "SHA384withECDSA"
+	SHA512withECDSA  = "SHA512withECDSA"
+)
+
+// RSASSA-PSS OID does not carry the hash function, so all the PSS algorithms share
+// it and are told apart by the hash declared in the algorithm parameters.
+var pssAliases = []string{"RSASSA-PSS", "rsassaPss", "RSA-PSS", "1.2.840.113549.1.1.10"}
+
+var defaultAlgorithms = []Algorithm{
+	{
+		Name:         SHA256withRSA,
+		Aliases:      []string{"sha256WithRSAEncryption", "RSA-SHA256", "1.2.840.113549.1.1.11"},
+		Hash:         crypto.SHA256,
+		Verifier:     VerifyPKCS1v15,
+		CircuitKeyID: "sha256",
+	},
+	{
+		Name:         SHA384withRSA,
+		Aliases:      []string{"sha384WithRSAEncryption", "RSA-SHA384", "1.2.840.113549.1.1.12"},
+		Hash:         crypto.SHA384,
+		Verifier:     VerifyPKCS1v15,
+		CircuitKeyID: "sha384",
+	},
+	{
+		Name:         SHA512withRSA,
+		Aliases:      []string{"sha512WithRSAEncryption", "RSA-SHA512", "1.2.840.113549.1.1.13"},
+		Hash:         crypto.SHA512,
+		Verifier:     VerifyPKCS1v15,
+		CircuitKeyID: "sha512",
+	},
+	{
+		Name:           SHA256withRSAPSS,
+		Aliases:        append([]string{"SHA256withRSA/PSS", "SHA256withRSAandMGF1"}, pssAliases...),
+		Hash:           crypto.SHA256,
+		Verifier:       VerifyPSS,
+		HashFromParams: PSSHash,
+		CircuitKeyID:   "sha256_rsapss",
+	},
+	{
+		Name:           SHA384withRSAPSS,
+		Aliases:        append([]string{"SHA384withRSA/PSS", "SHA384withRSAandMGF1"}, pssAliases...),
+		Hash:           crypto.SHA384,
+		Verifier:       VerifyPSS,
+		HashFromParams: PSSHash,
+		CircuitKeyID:   "sha384_rsapss",
+	},
+	{
+		Name:           SHA512withRSAPSS,
+		Aliases:        append([]string{"SHA512withRSA/PSS", "SHA512withRSAandMGF1"}, pssAliases...),
+		Hash:           crypto.SHA512,
+		Verifier:       VerifyPSS,
+		HashFromParams: PSSHash,
+		CircuitKeyID:   "sha512_rsapss",
+	},
+	{
+		Name:         SHA1withECDSA,
+		Aliases:      []string{"ecdsa-with-SHA1", "ECDSA-SHA1", "1.2.840.10045.4.1"},
+		Hash:         crypto.SHA1,
+		Verifier:     VerifyECDSA,
+		CircuitKeyID: "sha1",
+	},
+	{
+		Name:         SHA256withECDSA,
+		Aliases:      []string{"ecdsa-with-SHA256", "ECDSA-SHA256", "1.2.840.10045.4.3.2"},
+		Hash:         crypto.SHA256,
+		Verifier:     VerifyECDSA,
+		CircuitKeyID: "sha256",
+	},
+	{
+		Name:         SHA384withECDSA,
+		Aliases:      []string{"ecdsa-with-SHA384", "ECDSA-SHA384", "1.2.840.10045.4.3.3"},
+		Hash:         crypto.SHA384,
+		Verifier:     VerifyECDSA,
+		CircuitKeyID: "sha384",
+	},
+	{
+		Name:         SHA512withECDSA,
+		Aliases:      []string{"ecdsa-with-SHA512", "ECDSA-SHA512", "1.2.840.10045.4.3.4"},
+		Hash:         crypto.SHA512,
+		Verifier:     VerifyECDSA,
+		CircuitKeyID: "sha512",
+	},
+}
+
+// NewDefaultRegistry returns the registry of the signature algorithms used by the document signers.
+func NewDefaultRegistry() *Registry {
+	registry, err := NewRegistry(defaultAlgorithms...)
+	if err != nil {
+		panic(err)
+	}
+
+	return registry
+}
@@ -0,0 +1,166 @@
+package algorithms
+
+import (
+	"crypto"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signature algorithm")
+)
+
+// SignatureVerifier checks the signature over the digest produced by the hash function.
+// Parameters are the DER-encoded signature algorithm parameters, if any.
+type SignatureVerifier func(publicKey crypto.PublicKey, hash crypto.Hash, digest, signature, parameters []byte) error
+
+// ParametersHash extracts the hash function from the signature algorithm parameters for
+// the algorithms whose identifier does not fix it, e.g. RSASSA-PSS.
+type ParametersHash func(parameters []byte) (crypto.Hash, error)
+
+type Algorithm struct {
+	// Name is the canonical algorithm name, e.g. SHA256withECDSA
+	Name string
+	// Aliases are the other names and the dotted OIDs the algorithm is accepted by
+	Aliases        []string
+	Hash           crypto.Hash
+	Verifier       SignatureVerifier
+	HashFromParams ParametersHash
+	// CircuitKeyID is the verifier.verification_keys_paths entry with the circuit verification key
+	CircuitKeyID string
+}
+
+// Digest hashes the data with the algorithm hash function.
+func (a *Algorithm) Digest(data []byte) []byte {
+	h := a.Hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// Verify checks the signature over the signed data.
+func (a *Algorithm) Verify(publicKey crypto.PublicKey, signed, signature, parameters []byte) error {
+	return a.Verifier(publicKey, a.Hash, a.Digest(signed), signature, parameters)
+}
+
+type Registry struct {
+	algorithms []*Algorithm
+	aliases    map[string][]*Algorithm
+}
+
+func NewRegistry(algorithms ...Algorithm) (*Registry, error) {
+	registry := &Registry{
+		aliases: make(map[string][]*Algorithm),
+	}
+
+	for _, algorithm := range algorithms {
+		if err := registry.Register(algorithm); err != nil {
+			return nil, errors.Wrap(err, "failed to register algorithm", logan.F{
+				"algorithm": algorithm.Name,
+			})
+		}
+	}
+
+	return registry, nil
+}
+
+// Register adds the algorithm to the registry. Several algorithms may share an alias
+// only if all of them resolve the hash function from the algorithm parameters.
+func (r *Registry) Register(algorithm Algorithm) error {
+	if algorithm.Name == "" {
+		return errors.New("algorithm name is empty")
+	}
+	if !algorithm.Hash.Available() {
+		return fmt.Errorf("hash function %s is not available", algorithm.Hash)
+	}
+	if algorithm.Verifier == nil {
+		return errors.New("signature verifier is not set")
+	}
+
+	entry := &algorithm
+	for _, alias := range uniqueAliases(append([]string{algorithm.Name}, algorithm.Aliases...)) {
+		for _, registered := range r.aliases[alias] {
+			if registered.HashFromParams == nil || entry.HashFromParams == nil {
+				return fmt.Errorf("alias %s is already taken by %s", alias, registered.Name)
+			}
+			if registered.Hash == entry.Hash {
+				return fmt.Errorf("alias %s with hash %s is already taken by %s", alias, entry.Hash, registered.Name)
+			}
+		}
+
+		r.aliases[alias] = append(r.aliases[alias], entry)
+	}
+
+	r.algorithms = append(r.algorithms, entry)
+	return nil
+}
+
+// Lookup finds the algorithm by its name, alias or dotted OID.
+func (r *Registry) Lookup(name string, parameters []byte) (*Algorithm, error) {
+	candidates := r.aliases[normalizeAlias(name)]
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w %q, supported algorithms: %s",
+			ErrUnsupportedAlgorithm, name, strings.Join(r.Supported(), ", "))
+	}
+
+	for _, candidate := range candidates {
+		if candidate.HashFromParams == nil {
+			return candidate, nil
+		}
+
+		hash, err := candidate.HashFromParams(parameters)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get hash function from algorithm parameters")
+		}
+
+		if hash == candidate.Hash {
+			return candidate, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w %q with the provided parameters, supported algorithms: %s",
+		ErrUnsupportedAlgorithm, name, strings.Join(r.Supported(), ", "))
+}
+
+// Supported returns the canonical names of the registered algorithms.
+func (r *Registry) Supported() []string {
+	names := make([]string, 0, len(r.algorithms))
+	for _, algorithm := range r.algorithms {
+		names = append(names, algorithm.Name)
+	}
+	return names
+}
+
+func uniqueAliases(aliases []string) []string {
+	seen := make(map[string]struct{}, len(aliases))
+	result := make([]string, 0, len(aliases))
+
+	for _, alias := range aliases {
+		normalized := normalizeAlias(alias)
+		if _, ok := seen[normalized]; ok {
+			continue
+		}
+
+		seen[normalized] = struct{}{}
+		result = append(result, normalized)
+	}
+
+	return result
+}
+
+// normalizeAlias makes SHA256withRSA, sha256-with-rsa and SHA256WITHRSA the same alias,
+// dots are kept so OIDs stay unambiguous.
+func normalizeAlias(alias string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || unicode.IsDigit(r) {
+			return r
+		}
+		if unicode.IsLetter(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, alias)
+}
@@ -0,0 +1,153 @@
+package algorithms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/resources"
+)
+
+var (
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidMGF1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8}
+)
+
+var hashesListMap = map[string]crypto.Hash{
+	oidSHA1.String():   crypto.SHA1,
+	oidSHA256.String(): crypto.SHA256,
+	oidSHA384.String(): crypto.SHA384,
+	oidSHA512.String(): crypto.SHA512,
+}
+
+// UnsupportedKeyError is returned when the public key type does not fit the signature algorithm.
+type UnsupportedKeyError struct {
+	Expected string
+	Key      crypto.PublicKey
+}
+
+func (e *UnsupportedKeyError) Error() string {
+	return fmt.Sprintf("expected %s public key, got %T", e.Expected, e.Key)
+}
+
+// HashFromOID returns the hash function identified by the digest algorithm OID.
+func HashFromOID(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	hash, ok := hashesListMap[oid.String()]
+	if !ok {
+		return 0, fmt.Errorf("%s is not supported hash algorithm", oid)
+	}
+
+	return hash, nil
+}
+
+func VerifyPKCS1v15(publicKey crypto.PublicKey, hash crypto.Hash, digest, signature, _ []byte) error {
+	pubKey, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return &UnsupportedKeyError{Expected: "RSA", Key: publicKey}
+	}
+
+	return rsa.VerifyPKCS1v15(pubKey, hash, digest, signature)
+}
+
+func VerifyPSS(publicKey crypto.PublicKey, hash crypto.Hash, digest, signature, parameters []byte) error {
+	pubKey, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return &UnsupportedKeyError{Expected: "RSA", Key: publicKey}
+	}
+
+	opts, err := ParsePSSParameters(parameters)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse RSASSA-PSS parameters")
+	}
+
+	return rsa.VerifyPSS(pubKey, hash, digest, signature, opts)
+}
+
+func VerifyECDSA(publicKey crypto.PublicKey, _ crypto.Hash, digest, signature, _ []byte) error {
+	pubKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return &UnsupportedKeyError{Expected: "ECDSA", Key: publicKey}
+	}
+
+	if !ecdsa.VerifyASN1(pubKey, digest, signature) {
+		return errors.New("invalid ECDSA signature")
+	}
+
+	return nil
+}
+
+// PSSHash is the ParametersHash of the RSASSA-PSS algorithms.
+func PSSHash(parameters []byte) (crypto.Hash, error) {
+	opts, err := ParsePSSParameters(parameters)
+	if err != nil {
+		return 0, err
+	}
+
+	return opts.Hash, nil
+}
+
+// ParsePSSParameters decodes DER-encoded RSASSA-PSS-params into the verification options.
+func ParsePSSParameters(parameters []byte) (*rsa.PSSOptions, error) {
+	if len(parameters) == 0 {
+		return nil, errors.New("RSASSA-PSS algorithm parameters are required")
+	}
+
+	pssParams := resources.PSSParameters{}
+	rest, err := asn1.Unmarshal(parameters, &pssParams)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal RSASSA-PSS parameters")
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after RSASSA-PSS parameters")
+	}
+
+	hash, err := hashFromAlgorithmIdentifier(pssParams.Hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid RSASSA-PSS hash algorithm")
+	}
+
+	// Go only implements MGF1 driven by the same hash function as the message digest
+	mgfHash := crypto.SHA1
+	if len(pssParams.MGF.Algorithm) != 0 {
+		if !pssParams.MGF.Algorithm.Equal(oidMGF1) {
+			return nil, fmt.Errorf("unsupported mask generation function %s", pssParams.MGF.Algorithm)
+		}
+
+		mgfHashAlgorithm := pkix.AlgorithmIdentifier{}
+		if _, err := asn1.Unmarshal(pssParams.MGF.Parameters.FullBytes, &mgfHashAlgorithm); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal MGF1 parameters")
+		}
+
+		if mgfHash, err = hashFromAlgorithmIdentifier(mgfHashAlgorithm); err != nil {
+			return nil, errors.Wrap(err, "invalid MGF1 hash algorithm")
+		}
+	}
+	if mgfHash != hash {
+		return nil, errors.New("MGF1 hash algorithm differs from the message hash algorithm")
+	}
+
+	if pssParams.TrailerField != 1 {
+		return nil, fmt.Errorf("unsupported trailer field %d", pssParams.TrailerField)
+	}
+
+	return &rsa.PSSOptions{
+		SaltLength: pssParams.SaltLength,
+		Hash:       hash,
+	}, nil
+}
+
+func hashFromAlgorithmIdentifier(algorithm pkix.AlgorithmIdentifier) (crypto.Hash, error) {
+	if len(algorithm.Algorithm) == 0 {
+		return crypto.SHA1, nil
+	}
+
+	return HashFromOID(algorithm.Algorithm)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+)
+
+// AdminAuth allows the requests bearing the token of an operator and puts the operator in
+// the request context, all of them are rejected when no operator is configured.
+func AdminAuth(operators []config.OperatorConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+			// every token is compared, so the time does not tell which one is close
+			operator := ""
+			for _, candidate := range operators {
+				if subtle.ConstantTimeCompare([]byte(bearer), []byte(candidate.Token)) == 1 {
+					operator = candidate.Name
+				}
+			}
+			if operator == "" {
+				ape.RenderErr(w, problems.Unauthorized())
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(CtxOperator(operator)(r.Context())))
+		})
+	}
+}
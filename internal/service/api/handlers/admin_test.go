@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+)
+
+func TestAdminAuth(t *testing.T) {
+	operators := []config.OperatorConfig{{Name: "alice", Token: "alice-token"}, {Name: "bob", Token: "bob-token"}}
+
+	var operator string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operator = Operator(r)
+	})
+
+	cases := map[string]struct {
+		operators []config.OperatorConfig
+		token     string
+		want      string
+	}{
+		"alice":        {operators, "alice-token", "alice"},
+		"bob":          {operators, "bob-token", "bob"},
+		"unknown":      {operators, "carol-token", ""},
+		"no token":     {operators, "", ""},
+		"no operators": {nil, "", ""},
+	}
+	for name, c := range cases {
+		operator = ""
+		r := newTestRequest(http.MethodPost, "/admin/revocations", nil)
+		if c.token != "" {
+			r.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		w := httptest.NewRecorder()
+		AdminAuth(c.operators)(handler).ServeHTTP(w, r)
+		if c.want == "" && w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected %d, got %d", name, http.StatusUnauthorized, w.Code)
+		}
+		if operator != c.want {
+			t.Errorf("%s: expected operator %q, got %q", name, c.want, operator)
+		}
+	}
+}
@@ -3,57 +3,45 @@ package handlers
 import (
 	"bytes"
 	"crypto"
-	"crypto/ecdsa"
-	"crypto/rsa"
-	"crypto/sha1"
-	"crypto/sha256"
-	"encoding/asn1"
 	"encoding/hex"
-	"encoding/pem"
 	"fmt"
 	"math/big"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/jsonapi"
 	"github.com/google/uuid"
 	"github.com/iden3/go-iden3-crypto/poseidon"
-	"github.com/iden3/go-rapidsnark/verifier"
 	"github.com/rarimo/certificate-transparency-go/x509"
 	"gitlab.com/distributed_lab/ape"
 	"gitlab.com/distributed_lab/ape/problems"
+	"gitlab.com/distributed_lab/logan/v3"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 
 	"github.com/rarimo/passport-identity-provider/internal/config"
 	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/activeauth"
+	"github.com/rarimo/passport-identity-provider/internal/service/algorithms"
 	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
-	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
-	"github.com/rarimo/passport-identity-provider/resources"
+	"github.com/rarimo/passport-identity-provider/internal/service/certificates"
+	"github.com/rarimo/passport-identity-provider/internal/service/chipauth"
+	"github.com/rarimo/passport-identity-provider/internal/service/circuits"
+	"github.com/rarimo/passport-identity-provider/internal/service/cms"
+	"github.com/rarimo/passport-identity-provider/internal/service/groth16"
+	"github.com/rarimo/passport-identity-provider/internal/service/policy"
+	"github.com/rarimo/passport-identity-provider/internal/service/sod"
 )
 
-// Full list of the OpenSSL signature algorithms and hash-functions is provided here:
-// https://www.openssl.org/docs/man1.1.1/man3/SSL_CTX_set1_sigalgs_list.html
+// adultAge is the age of the isAdult credential subject field
+const adultAge = 18
 
+// Anti-cloning mechanisms of the claim attributes
 const (
-	SHA1   = "sha1"
-	SHA256 = "sha256"
-
-	SHA256withRSA   = "SHA256withRSA"
-	SHA1withECDSA   = "SHA1withECDSA"
-	SHA256withECDSA = "SHA256withECDSA"
+	AntiCloningActiveAuthentication = "active_authentication"
+	AntiCloningChipAuthentication   = "chip_authentication"
 )
 
-var algorithmsListMap = map[string]map[string]string{
-	"SHA1": {
-		"ECDSA": SHA1withECDSA,
-	},
-	"SHA256": {
-		"RSA":   SHA256withRSA,
-		"ECDSA": SHA256withECDSA,
-	},
-}
-
 func CreateIdentity(w http.ResponseWriter, r *http.Request) {
 	req, err := requests.NewCreateIdentityRequest(r)
 	if err != nil {
@@ -62,161 +50,285 @@ func CreateIdentity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	algorithm := signatureAlgorithm(req.Data.DocumentSOD.Algorithm)
-	if algorithm == "" {
-		Log(r).WithError(fmt.Errorf("%s is not a valid algorithm", req.Data.DocumentSOD.Algorithm)).Error("failed to select signature algorithm")
-		ape.RenderErr(w, problems.BadRequest(fmt.Errorf("%s is not a valid algorithm", req.Data.DocumentSOD.Algorithm))...)
+	eligibilityPolicy, err := Policies(r).Lookup(req.Data.Policy)
+	if err != nil {
+		Log(r).WithError(err).Error("failed to select policy")
+		ape.RenderErr(w, problems.BadRequest(validation.Errors{
+			"/data/policy": err,
+		})...)
 		return
 	}
 
-	signedAttributes, err := hex.DecodeString(req.Data.DocumentSOD.SignedAttributes)
+	state := VerifierState(r)
+
+	documentSOD, err := parseDocumentSOD(req.Data)
 	if err != nil {
-		Log(r).WithError(err).Error("failed to decode hex string")
+		Log(r).WithError(err).Error("failed to parse document SOD")
 		ape.RenderErr(w, problems.BadRequest(err)...)
 		return
 	}
 
-	encapsulatedContent, err := hex.DecodeString(req.Data.DocumentSOD.EncapsulatedContent)
+	algorithm, err := Algorithms(r).Lookup(documentSOD.Algorithm, documentSOD.AlgorithmParameters)
 	if err != nil {
-		Log(r).WithError(err).Error("failed to decode hex string")
-		ape.RenderErr(w, problems.BadRequest(err)...)
+		Log(r).WithError(err).Error("failed to select signature algorithm")
+		ape.RenderErr(w, problems.BadRequest(validation.Errors{
+			"/data/document_sod/algorithm": err,
+		})...)
 		return
 	}
 
-	if err := validateSignedAttributes(signedAttributes, encapsulatedContent, algorithm); err != nil {
+	if err := validateSignedAttributes(documentSOD.SignedAttributes, documentSOD.EncapsulatedContent, algorithm); err != nil {
 		Log(r).WithError(err).Error("failed to validate signed attributes")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	if err := verifySignature(documentSOD, algorithm); err != nil {
+		Log(r).WithError(err).Error("failed to verify signature")
+		if _, ok := errors.Cause(err).(*algorithms.UnsupportedKeyError); ok {
+			ape.RenderErr(w, problems.BadRequest(err)...)
+			return
+		}
 		ape.RenderErr(w, problems.InternalError())
 		return
 	}
 
-	cert, err := parseCertificate([]byte(req.Data.DocumentSOD.PemFile))
+	cfg := VerifierConfig(r)
+
+	circuit, err := state.Circuits.Select(algorithm.CircuitKeyID, req.Data.ZKProof.CircuitID, time.Now())
 	if err != nil {
-		Log(r).WithError(err).Error("failed to parse certificate")
-		ape.RenderErr(w, problems.InternalError())
+		Log(r).WithError(err).WithFields(logan.F{
+			"algorithm":  algorithm.Name,
+			"circuit_id": req.Data.ZKProof.CircuitID,
+		}).Error("failed to select circuit")
+		ape.RenderErr(w, problems.BadRequest(validation.Errors{
+			"/data/zkproof/circuit_id": err,
+		})...)
 		return
 	}
 
-	if err := verifySignature(req, cert, signedAttributes, algorithm); err != nil {
-		Log(r).WithError(err).Error("failed to verify signature")
-		ape.RenderErr(w, problems.InternalError())
+	pubSignals, err := circuit.Decode(req.Data.ZKProof.PubSignals)
+	if err != nil {
+		Log(r).WithError(err).WithField("circuit_id", circuit.ID).Error("failed to decode pub signals")
+		ape.RenderErr(w, problems.BadRequest(pubSignalsErrors(err))...)
 		return
 	}
 
-	cfg := VerifierConfig(r)
+	if err := Groth16Verifier(r).Verify(circuit, req.Data.ZKProof.ZKProof); err != nil {
+		Log(r).WithError(err).Error("failed to verify Groth16")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
 
-	switch algorithm {
-	case SHA1withECDSA:
-		if err := verifier.VerifyGroth16(req.Data.ZKProof, cfg.VerificationKeys[SHA1]); err != nil {
-			Log(r).WithError(err).Error("failed to verify Groth16")
-			ape.RenderErr(w, problems.BadRequest(err)...)
-			return
-		}
-	case SHA256withRSA, SHA256withECDSA:
-		if err := verifier.VerifyGroth16(req.Data.ZKProof, cfg.VerificationKeys[SHA256]); err != nil {
-			Log(r).WithError(err).Error("failed to verify Groth16")
-			ape.RenderErr(w, problems.BadRequest(err)...)
-			return
-		}
-	default:
-		Log(r).WithField("algorithm", req.Data.DocumentSOD.Algorithm).Debug("invalid signature algorithm")
-		ape.RenderErr(w, problems.BadRequest(errors.New("invalid signature algorithm"))...)
+	statementHash, err := groth16.StatementHash(documentSOD.SignedAttributes, req.Data.ZKProof.PubSignals)
+	if err != nil {
+		Log(r).WithError(err).Error("failed to hash proof statement")
+		ape.RenderErr(w, problems.BadRequest(err)...)
 		return
 	}
 
-	encapsulatedData := resources.EncapsulatedData{}
-	if _, err = asn1.Unmarshal(encapsulatedContent, &encapsulatedData); err != nil {
-		Log(r).WithError(err).Error("failed to unmarshal ASN.1")
+	nullifier, err := MasterQ(r).ProofNullifier().FilterBy("hash", statementHash).Get()
+	if err != nil {
+		Log(r).WithError(err).Error("failed to get proof nullifier")
 		ape.RenderErr(w, problems.InternalError())
 		return
 	}
+	if nullifier != nil {
+		Log(r).WithField("statement_hash", hex.EncodeToString(statementHash)).Error("proof statement is already used")
+		ape.RenderErr(w, problems.Conflict())
+		return
+	}
 
-	if err := validatePubSignals(cfg, req.Data, encapsulatedData.PrivateKey.El1.OctetStr.Bytes); err != nil {
-		Log(r).WithError(err).Error("failed to validate pub signals")
+	lds, err := sod.ParseSecurityObject(documentSOD.EncapsulatedContent)
+	if err != nil {
+		Log(r).WithError(err).Error("failed to parse LDS security object")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	if lds.HashAlgorithm != algorithm.Hash {
+		err := fmt.Errorf("LDS hash algorithm %s differs from the %s signature algorithm", lds.HashAlgorithm, algorithm.Name)
+		Log(r).WithError(err).Error("invalid LDS security object")
 		ape.RenderErr(w, problems.BadRequest(err)...)
 		return
 	}
 
-	if err := validateCert(cert, cfg.MasterCerts); err != nil {
-		Log(r).WithError(err).Error("failed to validate certificate")
+	dg1Hash, err := lds.DataGroupHash(sod.DG1)
+	if err != nil {
+		Log(r).WithError(err).Error("failed to get DG1 hash")
 		ape.RenderErr(w, problems.BadRequest(err)...)
 		return
 	}
 
-	masterQ := MasterQ(r)
+	dg2Hash, err := lds.DataGroupHash(sod.DG2)
+	if err != nil {
+		Log(r).WithError(err).Error("failed to get DG2 hash")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
 
-	identityExpiration, err := getExpirationTimeFromPubSignals(req.Data.ZKProof.PubSignals)
+	now := time.Now().UTC()
+	if err := validatePubSignals(cfg, pubSignals, dg1Hash, now); err != nil {
+		Log(r).WithError(err).Error("failed to validate pub signals")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	identityExpiration, err := getExpirationTimeFromPubSignals(pubSignals, now)
 	if err != nil {
-		Log(r).WithError(err).Error("failed to get expiration time")
+		Log(r).WithError(err).Error("failed to get identity expiration")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	if err := validateDocumentExpiration(cfg, *identityExpiration, now); err != nil {
+		Log(r).WithError(err).WithField("expiration", identityExpiration).Error("document is expired")
 		ape.RenderErr(w, problems.BadRequest(err)...)
 		return
 	}
 
-	issuingAuthority, err := strconv.Atoi(req.Data.ZKProof.PubSignals[2])
+	resolution, err := state.TrustStore.Resolve(documentSOD.Certificate)
+	if err != nil {
+		Log(r).WithError(err).WithFields(resolution.Fields()).Error("failed to validate certificate")
+		ape.RenderErr(w, problems.BadRequest(validation.Errors{
+			certificateField(req.Data): errors.Wrap(err, "invalid certificate"),
+		})...)
+		return
+	}
+	Log(r).WithFields(resolution.Fields()).Debug("document signer CSCA resolved")
+
+	if err := CRLChecker(r).Check(documentSOD.Certificate); err != nil {
+		Log(r).WithError(err).WithFields(resolution.Fields()).Error("document signer certificate failed the CRL check")
+		ape.RenderErr(w, problems.BadRequest(validation.Errors{
+			certificateField(req.Data): err,
+		})...)
+		return
+	}
+
+	if err := eligibilityPolicy.Evaluate(policy.Subject{
+		Age:          pubSignals.Age,
+		Country:      resolution.Country,
+		DocumentType: pubSignals.DocumentType,
+	}); err != nil {
+		Log(r).WithError(err).WithField("policy", eligibilityPolicy.Name).Error("registration is not eligible")
+		ape.RenderErr(w, problems.BadRequest(validation.Errors{
+			"/data/policy": err,
+		})...)
+		return
+	}
+
+	activeAuthenticated, err := verifyActiveAuthentication(cfg, resolution.Country, lds, req.Data.ActiveAuthentication)
 	if err != nil {
-		Log(r).WithError(err).Error("failed to convert string to int")
+		Log(r).WithError(err).WithField("ds_country", resolution.Country).Error("failed to verify active authentication")
+		ape.RenderErr(w, problems.BadRequest(validation.Errors{
+			"/data/active_authentication": err,
+		})...)
+		return
+	}
+
+	terminalKey, err := challengeTerminalKey(MasterQ(r), req.Data)
+	if err != nil {
+		Log(r).WithError(err).Error("failed to get chip authentication terminal key")
 		ape.RenderErr(w, problems.InternalError())
 		return
 	}
 
-	var claimID string
-	iss := Issuer(r)
-	vaultClient := VaultClient(r)
+	chipAuthenticated, err := verifyChipAuthentication(lds, req.Data.ChipAuthentication, terminalKey)
+	if err != nil {
+		Log(r).WithError(err).Error("failed to verify chip authentication")
+		ape.RenderErr(w, problems.BadRequest(validation.Errors{
+			"/data/chip_authentication": err,
+		})...)
+		return
+	}
+
+	challengeNonce, err := registrationChallenge(pubSignals, req.Data, activeAuthenticated, chipAuthenticated)
+	if err != nil {
+		field := "/data/active_authentication/challenge"
+		if chipAuthenticated {
+			field = "/data/chip_authentication/challenge"
+		}
+		Log(r).WithError(err).Error("invalid registration challenge")
+		ape.RenderErr(w, problems.BadRequest(validation.Errors{
+			field: err,
+		})...)
+		return
+	}
+	if challengeNonce == nil && ChallengeConfig(r).Required {
+		err := fmt.Errorf("neither circuit %s nor chip anti-cloning mechanisms commit to the registration challenge", circuit.ID)
+		Log(r).WithError(err).Error("registration challenge is required")
+		ape.RenderErr(w, problems.BadRequest(validation.Errors{
+			"/data/zkproof/circuit_id": err,
+		})...)
+		return
+	}
 
-	blinder, err := vaultClient.Blinder()
+	blinder, err := VaultClient(r).Blinder()
 	if err != nil {
 		Log(r).WithError(err).Error("failed to get blinder from the vault")
 		ape.RenderErr(w, problems.InternalError())
 		return
 	}
 
-	hash, err := signedAttributesPoseidonHash(req.Data.DocumentSOD.SignedAttributes, blinder)
+	hash, err := signedAttributesPoseidonHash(documentSOD.SignedAttributes, blinder)
 	if err != nil {
 		Log(r).WithError(err).Error("failed to get signed attributes Poseidon hash")
 		ape.RenderErr(w, problems.InternalError())
 		return
 	}
 
-	var userId *string
-	if err := masterQ.Transaction(func(db data.MasterQ) error {
-		// check if there are any claims for this document already
-		claimsToRevoke, err := db.Claim().ResetFilter().
-			FilterBy("document_hash", hash.String()).
-			ForUpdate().
-			Select()
-		if err != nil {
+	claimRequest := data.ClaimRequest{
+		ID:               uuid.New(),
+		Status:           data.ClaimRequestPending,
+		UserID:           req.Data.UserID,
+		UserDID:          req.Data.ID.String(),
+		UserAddress:      req.Data.UserAddress,
+		DocumentHash:     hash.String(),
+		IssuingAuthority: pubSignals.IssuingAuthority,
+		IsAdult:          pubSignals.Age >= adultAge,
+		Expiration:       identityExpiration,
+		DG2Hash:          dg2Hash,
+		Policy:           eligibilityPolicy.Name,
+		DSSerial:         documentSOD.Certificate.SerialNumber.Text(16),
+		DSCountry:        resolution.Country,
+
+		ActiveAuthentication: activeAuthenticated,
+		ChipAuthentication:   chipAuthenticated,
+
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := MasterQ(r).Transaction(func(db data.MasterQ) error {
+		// the same statement submitted concurrently is stopped here
+		if err := db.ProofNullifier().Insert(data.ProofNullifier{
+			Hash:         statementHash,
+			DocumentHash: hash.String(),
+		}); err != nil {
+			if errors.Cause(err) == data.ErrProofNullifierExists {
+				ape.RenderErr(w, problems.Conflict())
+				return errors.Wrap(err, "proof statement is already used")
+			}
 			ape.RenderErr(w, problems.InternalError())
-			return errors.Wrap(err, "failed to get claim")
+			return errors.Wrap(err, "failed to insert proof nullifier")
 		}
 
-		// revoke if so
-		for _, claimToRevoke := range claimsToRevoke {
-			userIdRaw := req.Data.UserID.String()
-			userId = &userIdRaw
-
-			//timeoutExpiration := claimToRevoke.CreatedAt.UTC().Add(cfg.RegistrationTimeout)
-			//if time.Now().UTC().Before(timeoutExpiration) {
-			//	ape.RenderErr(w, problems.TooManyRequests())
-			//	return errors.New("registration timeout is not expired")
-			//}
-
-			if err := revokeOutdatedClaim(db, iss, claimToRevoke.ID); err != nil {
+		if challengeNonce != nil {
+			if err := consumeChallenge(db, req.Data, challengeNonce); err != nil {
+				if errors.Cause(err) == errChallengeNotFound {
+					ape.RenderErr(w, problems.BadRequest(err)...)
+					return err
+				}
 				ape.RenderErr(w, problems.InternalError())
-				return errors.Wrap(err, "failed to revoke outdated claim")
+				return errors.Wrap(err, "failed to consume challenge")
 			}
 		}
 
-		claimID, err = iss.IssueVotingClaim(
-			req.Data.ID.String(), int64(issuingAuthority), true, identityExpiration,
-			encapsulatedData.PrivateKey.El2.OctetStr.Bytes, blinder, req.Data.UserAddress, req.Data.UserID, hash.String(),
-		)
-		if err != nil {
-			ape.RenderErr(w, problems.InternalError())
-			return errors.Wrap(err, "failed to issue voting claim")
-		}
-
-		if err := writeDataToDB(db, req, claimID, iss.DID(), hash.String()); err != nil {
+		// the claim is issued by the issuance worker once the registration is committed
+		if err := db.ClaimRequest().Insert(claimRequest); err != nil {
 			ape.RenderErr(w, problems.InternalError())
-			return errors.Wrap(err, "failed to write proof to the database")
+			return errors.Wrap(err, "failed to insert claim request")
 		}
 
 		return nil
@@ -226,102 +338,96 @@ func CreateIdentity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := resources.ClaimResponse{
-		Data: resources.Claim{
-			Key: resources.Key{
-				ID:   claimID,
-				Type: resources.CLAIMS,
-			},
-			Attributes: resources.ClaimAttributes{
-				ClaimId:   claimID,
-				IssuerDid: iss.DID(),
-				UserId:    userId,
-			},
-		},
-	}
-
-	ape.Render(w, response)
+	Log(r).WithField("claim_request", claimRequest.ID).Info("registration accepted")
+	renderAccepted(w, newClaimRequestResponse(claimRequest))
 }
 
-func parseCertificate(pemFile []byte) (*x509.Certificate, error) {
-	block, _ := pem.Decode(pemFile)
-	if block == nil {
-		return nil, fmt.Errorf("invalid certificate: invalid PEM")
-	}
-
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse certificate")
-	}
-
-	return cert, nil
+// renderAccepted renders the response with 202 Accepted, ape.Render leaves the status 200.
+func renderAccepted(w http.ResponseWriter, res interface{}) {
+	w.Header().Set("content-type", jsonapi.MediaType)
+	w.WriteHeader(http.StatusAccepted)
+	ape.Render(w, res)
 }
 
-func validateSignedAttributes(signedAttributes, encapsulatedContent []byte, algorithm string) error {
-	signedAttributesASN1 := make([]asn1.RawValue, 0)
+// parseDocumentSOD takes the document SOD either from the raw EF.SOD or from the
+// fields the client has already split it into.
+func parseDocumentSOD(requestData requests.CreateIdentityRequestData) (*sod.SOD, error) {
+	if requestData.SOD != "" {
+		raw, err := sod.Decode(requestData.SOD)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode EF.SOD")
+		}
+
+		documentSOD, err := sod.Parse(raw)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse EF.SOD")
+		}
 
-	if _, err := asn1.UnmarshalWithParams(signedAttributes, &signedAttributesASN1, "set"); err != nil {
-		return errors.Wrap(err, "failed to unmarshal ASN1 with params")
+		return documentSOD, nil
 	}
 
-	if len(signedAttributesASN1) == 0 {
-		return errors.New("signed attributes amount is 0")
+	signedAttributes, err := hex.DecodeString(requestData.DocumentSOD.SignedAttributes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode signed attributes hex string")
 	}
 
-	digestAttr := resources.DigestAttribute{}
-	if _, err := asn1.Unmarshal(signedAttributesASN1[len(signedAttributesASN1)-1].FullBytes, &digestAttr); err != nil {
-		return errors.Wrap(err, "failed to unmarshal ASN1")
+	algorithmParameters, err := hex.DecodeString(requestData.DocumentSOD.AlgorithmParameters)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode algorithm parameters hex string")
 	}
 
-	d := make([]byte, 0)
-	switch algorithm {
-	case SHA1withECDSA:
-		h := sha1.New()
-		h.Write(encapsulatedContent)
-		d = h.Sum(nil)
-	case SHA256withRSA, SHA256withECDSA:
-		h := sha256.New()
-		h.Write(encapsulatedContent)
-		d = h.Sum(nil)
-	default:
-		return errors.New(fmt.Sprintf("%s is not supported algorithm", algorithm))
+	signature, err := hex.DecodeString(requestData.DocumentSOD.Signature)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode signature hex string")
 	}
 
-	if len(digestAttr.Digest) == 0 {
-		return errors.New("signed attributes digest values amount is 0")
+	encapsulatedContent, err := hex.DecodeString(requestData.DocumentSOD.EncapsulatedContent)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode encapsulated content hex string")
 	}
 
-	if !bytes.Equal(digestAttr.Digest[0].Bytes, d) {
-		return errors.New("digest signed attribute is not equal to encapsulated content hash")
+	cert, err := parseCertificate([]byte(requestData.DocumentSOD.PemFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse certificate")
 	}
-	return nil
+
+	return &sod.SOD{
+		SignedAttributes:    signedAttributes,
+		Algorithm:           requestData.DocumentSOD.Algorithm,
+		AlgorithmParameters: algorithmParameters,
+		Signature:           signature,
+		Certificate:         cert,
+		EncapsulatedContent: encapsulatedContent,
+	}, nil
 }
 
-func signatureAlgorithm(passedAlgorithm string) string {
-	if strings.Contains(strings.ToUpper(passedAlgorithm), "PSS") {
-		return "" // RSA-PSS is not currently supported
+func parseCertificate(pemFile []byte) (*x509.Certificate, error) {
+	cert, err := certificates.ParsePEM(pemFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse certificate")
 	}
 
-	for hashFunc, signatureAlgorithms := range algorithmsListMap {
-		if strings.Contains(strings.ToUpper(passedAlgorithm), hashFunc) {
-			for signatureAlgo, algorithmName := range signatureAlgorithms {
-				if strings.Contains(strings.ToUpper(passedAlgorithm), signatureAlgo) {
-					return algorithmName
-				}
-			}
-		}
-	}
-	return ""
+	return cert, nil
 }
 
-func signedAttributesPoseidonHash(signedAttributes string, blinder *big.Int) (*big.Int, error) {
-	signedAttributesBytes, err := hex.DecodeString(signedAttributes)
+// validateSignedAttributes checks the message digest signed attribute is the hash of the
+// encapsulated content.
+func validateSignedAttributes(signedAttributes, encapsulatedContent []byte, algorithm *algorithms.Algorithm) error {
+	digest, err := cms.MessageDigest(signedAttributes)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to decode hex string")
+		return errors.Wrap(err, "failed to get message digest")
+	}
+
+	if !bytes.Equal(digest, algorithm.Digest(encapsulatedContent)) {
+		return errors.New("digest signed attribute is not equal to encapsulated content hash")
 	}
 
+	return nil
+}
+
+func signedAttributesPoseidonHash(signedAttributes []byte, blinder *big.Int) (*big.Int, error) {
 	dataToHash := make([]byte, 0)
-	dataToHash = append(dataToHash, signedAttributesBytes...)
+	dataToHash = append(dataToHash, signedAttributes...)
 	dataToHash = append(dataToHash, blinder.Bytes()...)
 
 	hash, err := poseidon.HashBytes(dataToHash)
@@ -332,228 +438,312 @@ func signedAttributesPoseidonHash(signedAttributes string, blinder *big.Int) (*b
 	return hash, nil
 }
 
-func revokeOutdatedClaim(db data.MasterQ, iss *issuer.Issuer, claimID uuid.UUID) error {
-	cred, err := iss.GetCredential(claimID)
+// verifyActiveAuthentication checks the chip signature of the challenge against the DG15 key
+// if the document signer country mode requires or allows it, and reports whether it was performed.
+func verifyActiveAuthentication(
+	cfg *config.VerifierConfig, country string, lds *sod.SecurityObject, aa *requests.ActiveAuthentication,
+) (bool, error) {
+	switch cfg.ActiveAuthenticationMode(country) {
+	case config.ActiveAuthenticationIgnored:
+		return false, nil
+	case config.ActiveAuthenticationRequired:
+		if aa == nil {
+			return false, fmt.Errorf("active authentication is required for %s documents", country)
+		}
+	}
+	if aa == nil {
+		return false, nil
+	}
+
+	dg15, err := hex.DecodeString(aa.DG15)
 	if err != nil {
-		return errors.Wrap(err, "failed to get credential")
+		return false, errors.Wrap(err, "failed to decode DG15")
 	}
 
-	if !cred.Revoked {
-		if err := iss.RevokeClaim(cred.CredentialStatus.RevocationNonce); err != nil {
-			return errors.Wrap(err, "failed to revoke claim")
-		}
+	if err := validateDataGroupHash(lds, sod.DG15, dg15); err != nil {
+		return false, err
 	}
 
-	if err := db.Claim().DeleteByID(claimID); err != nil {
-		return errors.Wrap(err, "failed to delete claim")
+	publicKey, err := activeauth.ParseDG15(dg15)
+	if err != nil {
+		return false, err
 	}
 
-	return nil
-}
+	var signatureHash crypto.Hash
+	if aa.HashAlgorithm != "" {
+		if signatureHash, err = activeauth.HashByName(aa.HashAlgorithm); err != nil {
+			return false, err
+		}
+	}
 
-func writeDataToDB(db data.MasterQ, req requests.CreateIdentityRequest, claimIDStr, issuerDID, hash string) error {
-	claimID, err := uuid.Parse(claimIDStr)
+	nonce, ok := new(big.Int).SetString(aa.Challenge, 10)
+	if !ok {
+		return false, errors.New("invalid challenge")
+	}
+
+	signature, err := hex.DecodeString(aa.Signature)
 	if err != nil {
-		return errors.Wrap(err, "failed to parse uuid")
+		return false, errors.Wrap(err, "failed to decode signature")
 	}
 
-	if err := db.Claim().Insert(data.Claim{
-		ID:           claimID,
-		UserDID:      req.Data.ID.String(),
-		UserID:       req.Data.UserID,
-		UserAddress:  req.Data.UserAddress,
-		IssuerDID:    issuerDID,
-		DocumentHash: hash,
-	}); err != nil {
-		return errors.Wrap(err, "failed to insert claim in the database")
+	if err := activeauth.Verify(publicKey, activeauth.Challenge(nonce), signature, signatureHash); err != nil {
+		return false, err
 	}
 
-	return nil
+	return true, nil
 }
 
-func verifySignature(req requests.CreateIdentityRequest, cert *x509.Certificate, signedAttributes []byte, algo string) error {
-	signature, err := hex.DecodeString(req.Data.DocumentSOD.Signature)
+// verifyChipAuthentication checks the chip authentication transcript against the DG14 keys
+// and the terminal key of the registration challenge, and reports whether it was performed.
+func verifyChipAuthentication(lds *sod.SecurityObject, ca *requests.ChipAuthentication, terminalKey *chipauth.TerminalKey) (bool, error) {
+	if ca == nil {
+		return false, nil
+	}
+	if terminalKey == nil {
+		return false, errors.New("registration challenge with the terminal key is not found or expired")
+	}
+
+	dg14, err := hex.DecodeString(ca.DG14)
 	if err != nil {
-		return errors.Wrap(err, "failed to decode hex string")
+		return false, errors.Wrap(err, "failed to decode DG14")
 	}
 
-	switch algo {
-	case SHA256withRSA:
-		pubKey := cert.PublicKey.(*rsa.PublicKey)
+	if err := validateDataGroupHash(lds, sod.DG14, dg14); err != nil {
+		return false, err
+	}
 
-		h := sha256.New()
-		h.Write(signedAttributes)
-		d := h.Sum(nil)
+	keys, err := chipauth.ParseDG14(dg14)
+	if err != nil {
+		return false, err
+	}
 
-		if err := rsa.VerifyPKCS1v15(pubKey, crypto.SHA256, d, signature); err != nil {
-			return errors.Wrap(err, "failed to verify SHA256 with RSA signature")
+	response, err := hex.DecodeString(ca.Response)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to decode response")
+	}
+
+	// the chip does not tell which of its keys it has used, any of them will do
+	for _, key := range keys {
+		if err = chipauth.Verify(key, terminalKey, response); err == nil {
+			return true, nil
 		}
-	case SHA1withECDSA:
-		pubKey := cert.PublicKey.(*ecdsa.PublicKey)
+	}
+
+	return false, err
+}
 
-		h := sha1.New()
-		h.Write(signedAttributes)
-		d := h.Sum(nil)
+// challengeTerminalKey returns the chip authentication terminal key of the registration
+// challenge, nil if there is no such challenge or it was issued without the key. The
+// challenge itself is consumed along with the registration.
+func challengeTerminalKey(db data.MasterQ, requestData requests.CreateIdentityRequestData) (*chipauth.TerminalKey, error) {
+	if requestData.ChipAuthentication == nil {
+		return nil, nil
+	}
 
-		if !ecdsa.VerifyASN1(pubKey, d, signature) {
-			return errors.New("failed to verify SHA1 with ECDSA signature")
-		}
-	case SHA256withECDSA:
-		pubKey := cert.PublicKey.(*ecdsa.PublicKey)
+	challenge, err := db.Challenge().
+		FilterBy("nonce", requestData.ChipAuthentication.Challenge).
+		FilterBy("user_did", requestData.ID.String()).
+		FilterBy("user_address", requestData.UserAddress).
+		Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get challenge")
+	}
+	if challenge == nil || !time.Now().UTC().Before(challenge.ExpiresAt) ||
+		challenge.CATerminalCurve == nil || challenge.CATerminalKey == nil {
+		return nil, nil
+	}
 
-		h := sha256.New()
-		h.Write(signedAttributes)
-		d := h.Sum(nil)
+	curve, err := certificates.CurveByName(*challenge.CATerminalCurve)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid terminal key curve")
+	}
 
-		if !ecdsa.VerifyASN1(pubKey, d, signature) {
-			return errors.New("failed to verify SHA256 with ECDSA signature")
-		}
-	default:
-		return errors.New(fmt.Sprintf("%s is unsupported algorithm", req.Data.DocumentSOD.Algorithm))
+	d, ok := new(big.Int).SetString(*challenge.CATerminalKey, 16)
+	if !ok {
+		return nil, errors.New("invalid terminal private key")
 	}
 
-	return nil
+	return &chipauth.TerminalKey{Curve: curve, D: d}, nil
 }
 
-func validateCert(cert *x509.Certificate, masterCertsPem []byte) error {
-	roots := x509.NewCertPool()
-	roots.AppendCertsFromPEM(masterCertsPem)
-
-	foundCerts, err := cert.Verify(x509.VerifyOptions{
-		Roots: roots,
-	})
+// validateDataGroupHash checks the data group content against its LDS security object hash.
+func validateDataGroupHash(lds *sod.SecurityObject, number int, content []byte) error {
+	expected, err := lds.DataGroupHash(number)
 	if err != nil {
-		return fmt.Errorf("invalid certificate: %w", err)
+		return errors.Wrap(err, fmt.Sprintf("failed to get DG%d hash", number))
 	}
 
-	if len(foundCerts) == 0 {
-		return fmt.Errorf("invalid certificate: no valid certificate found")
+	hash := lds.HashAlgorithm.New()
+	hash.Write(content)
+	if !bytes.Equal(hash.Sum(nil), expected) {
+		return fmt.Errorf("DG%d hash differs from the LDS security object one", number)
 	}
 
 	return nil
 }
 
-func validatePubSignals(
-	cfg *config.VerifierConfig, requestData requests.CreateIdentityRequestData, dg1 []byte,
-) error {
-	if err := validatePubSignalsDG1Hash(dg1, requestData.ZKProof.PubSignals); err != nil {
-		return errors.Wrap(err, "failed to validate DG1 hash")
+// registrationChallenge returns the challenge nonce the registration commits to through
+// the proof or the chip anti-cloning mechanisms, nil if it does not. All of them must
+// commit to the same one.
+func registrationChallenge(
+	pubSignals *circuits.PubSignals, requestData requests.CreateIdentityRequestData, activeAuthenticated, chipAuthenticated bool,
+) (*big.Int, error) {
+	nonces := make([]*big.Int, 0, 3)
+	if pubSignals.Challenge != nil {
+		nonces = append(nonces, pubSignals.Challenge)
+	}
+	if activeAuthenticated {
+		nonce, _ := new(big.Int).SetString(requestData.ActiveAuthentication.Challenge, 10)
+		nonces = append(nonces, nonce)
+	}
+	if chipAuthenticated {
+		nonce, _ := new(big.Int).SetString(requestData.ChipAuthentication.Challenge, 10)
+		nonces = append(nonces, nonce)
 	}
 
-	if err := validatePubSignalsCurrentDate(requestData.ZKProof.PubSignals); err != nil {
-		return fmt.Errorf("invalid current date: %w", err)
+	if len(nonces) == 0 {
+		return nil, nil
 	}
+	for _, nonce := range nonces[1:] {
+		if nonce.Cmp(nonces[0]) != 0 {
+			return nil, errors.New("proof and chip anti-cloning mechanisms commit to different challenges")
+		}
+	}
+
+	return nonces[0], nil
+}
 
-	if err := validatePubSignalsAge(cfg, requestData.ZKProof.PubSignals[9]); err != nil {
-		return errors.Wrap(err, "failed to validate pub signals age")
+// antiCloningMechanisms lists the mechanisms the chip proved it is genuine by.
+func antiCloningMechanisms(activeAuthenticated, chipAuthenticated bool) []string {
+	mechanisms := make([]string, 0, 2)
+	if activeAuthenticated {
+		mechanisms = append(mechanisms, AntiCloningActiveAuthentication)
+	}
+	if chipAuthenticated {
+		mechanisms = append(mechanisms, AntiCloningChipAuthentication)
 	}
 
-	return nil
+	return mechanisms
 }
 
-func validatePubSignalsDG1Hash(dg1 []byte, pubSignals []string) error {
-	ints, err := stringsToArrayBigInt([]string{pubSignals[0], pubSignals[1]})
+var errChallengeNotFound = errors.New("registration challenge is not found or expired")
+
+// consumeChallenge deletes the challenge the proof commits to, it must have been issued
+// to the same DID and address and must not be expired.
+func consumeChallenge(db data.MasterQ, requestData requests.CreateIdentityRequestData, nonce *big.Int) error {
+	challenge, err := db.Challenge().
+		FilterBy("nonce", nonce.String()).
+		FilterBy("user_did", requestData.ID.String()).
+		FilterBy("user_address", requestData.UserAddress).
+		ForUpdate().
+		Get()
 	if err != nil {
-		return errors.Wrap(err, "failed to convert strings to big integers")
+		return errors.Wrap(err, "failed to get challenge")
+	}
+	if challenge == nil || !time.Now().UTC().Before(challenge.ExpiresAt) {
+		return errChallengeNotFound
 	}
 
-	hashBytes := make([]byte, 0)
-	hashBytes = append(hashBytes, ints[0].Bytes()...)
-	hashBytes = append(hashBytes, ints[1].Bytes()...)
-
-	if !bytes.Equal(dg1, hashBytes) {
-		return errors.New("encapsulated data and proof pub signals hashes are different")
+	if err := db.Challenge().DeleteByNonce(challenge.Nonce); err != nil {
+		return errors.Wrap(err, "failed to delete challenge")
 	}
 
 	return nil
 }
 
-func validatePubSignalsCurrentDate(pubSignals []string) error {
-	year, err := strconv.Atoi(pubSignals[3])
+func verifySignature(documentSOD *sod.SOD, algorithm *algorithms.Algorithm) error {
+	err := algorithm.Verify(
+		documentSOD.Certificate.PublicKey,
+		documentSOD.SignedAttributes,
+		documentSOD.Signature,
+		documentSOD.AlgorithmParameters,
+	)
 	if err != nil {
-		return fmt.Errorf("invalid year: %w", err)
+		return errors.Wrap(err, fmt.Sprintf("failed to verify %s signature", algorithm.Name))
 	}
 
-	month, err := strconv.Atoi(pubSignals[4])
-	if err != nil {
-		return fmt.Errorf("invalid month: %w", err)
-	}
+	return nil
+}
 
-	day, err := strconv.Atoi(pubSignals[5])
-	if err != nil {
-		return fmt.Errorf("invalid day: %w", err)
+// certificateField returns the request field the document signer certificate came from.
+func certificateField(requestData requests.CreateIdentityRequestData) string {
+	if requestData.DocumentSOD == nil {
+		return "/data/sod"
 	}
 
-	currentTime := time.Now().UTC()
+	return "/data/document_sod/pem_file"
+}
 
-	if currentTime.Year() != (2000 + year) {
-		return fmt.Errorf("invalid year, expected %d, got %d", currentTime.Year(), 2000+year)
+func validatePubSignals(cfg *config.VerifierConfig, pubSignals *circuits.PubSignals, dg1 []byte, now time.Time) error {
+	if err := validatePubSignalsDG1Hash(dg1, pubSignals); err != nil {
+		return errors.Wrap(err, "failed to validate DG1 hash")
 	}
 
-	if currentTime.Month() != time.Month(month) {
-		return fmt.Errorf("invalid month, expected %d, got %d", currentTime.Month(), month)
+	if err := validatePubSignalsCurrentDate(cfg, pubSignals, now); err != nil {
+		return fmt.Errorf("invalid current date: %w", err)
 	}
 
-	if currentTime.Day() != day {
-		return fmt.Errorf("invalid day, expected %d, got %d", currentTime.Day(), day)
+	return nil
+}
+
+func validatePubSignalsDG1Hash(dg1 []byte, pubSignals *circuits.PubSignals) error {
+	hashBytes := make([]byte, 0)
+	hashBytes = append(hashBytes, pubSignals.DG1HashHi.Bytes()...)
+	hashBytes = append(hashBytes, pubSignals.DG1HashLo.Bytes()...)
+
+	if !bytes.Equal(dg1, hashBytes) {
+		return errors.New("encapsulated data and proof pub signals hashes are different")
 	}
 
 	return nil
 }
 
-func validatePubSignalsAge(cfg *config.VerifierConfig, agePubSignal string) error {
-	age, err := strconv.Atoi(agePubSignal)
+// validatePubSignalsCurrentDate checks the proof current date day overlaps the configured
+// window around now, the user may be ahead or behind UTC.
+func validatePubSignalsCurrentDate(cfg *config.VerifierConfig, pubSignals *circuits.PubSignals, now time.Time) error {
+	date, err := pubSignals.CurrentDate.Time(now)
 	if err != nil {
-		return errors.Wrap(err, "failed to convert pub input to int")
+		return err
 	}
-	if age < cfg.AllowedAge {
-		return errors.New("invalid age")
+
+	if date.After(now.Add(cfg.CurrentDateWindow)) || !date.AddDate(0, 0, 1).After(now.Add(-cfg.CurrentDateWindow)) {
+		return fmt.Errorf("%s is out of the %s window around %s",
+			date.Format(time.DateOnly), cfg.CurrentDateWindow, now.Format(time.RFC3339))
 	}
+
 	return nil
 }
 
-func getExpirationTimeFromPubSignals(pubSignals []string) (*time.Time, error) {
-	year, err := strconv.Atoi(pubSignals[6])
+// getExpirationTimeFromPubSignals returns the end of the document expiry date, the document
+// is valid through the whole day.
+func getExpirationTimeFromPubSignals(pubSignals *circuits.PubSignals, now time.Time) (*time.Time, error) {
+	expiryDate, err := pubSignals.ExpiryDate.Time(now)
 	if err != nil {
-		return nil, fmt.Errorf("invalid year: %w", err)
+		return nil, fmt.Errorf("invalid expiry date: %w", err)
 	}
 
-	month, err := strconv.Atoi(pubSignals[7])
-	if err != nil {
-		return nil, fmt.Errorf("invalid month: %w", err)
-	}
+	expiration := expiryDate.AddDate(0, 0, 1)
+	return &expiration, nil
+}
 
-	day, err := strconv.Atoi(pubSignals[8])
-	if err != nil {
-		return nil, fmt.Errorf("invalid day: %w", err)
+// validateDocumentExpiration rejects the documents that are expired or expire within the
+// configured grace window.
+func validateDocumentExpiration(cfg *config.VerifierConfig, expiration, now time.Time) error {
+	if !expiration.After(now.Add(cfg.ExpiryGraceWindow)) {
+		return fmt.Errorf("document expires at %s, it must be valid for %s at least",
+			expiration.Format(time.RFC3339), cfg.ExpiryGraceWindow)
 	}
 
-	expirationDate := time.Date(2000+year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
-
-	return &expirationDate, nil
+	return nil
 }
 
-func stringsToArrayBigInt(publicSignals []string) ([]*big.Int, error) {
-	p := make([]*big.Int, 0, len(publicSignals))
-	for _, s := range publicSignals {
-		sb, err := stringToBigInt(s)
-		if err != nil {
-			return nil, err
+// pubSignalsErrors points the public signals decoding error to the request field.
+func pubSignalsErrors(err error) validation.Errors {
+	if signalErr, ok := err.(*circuits.SignalError); ok {
+		return validation.Errors{
+			fmt.Sprintf("/data/zkproof/pub_signals/%d", signalErr.Index): signalErr,
 		}
-		p = append(p, sb)
 	}
-	return p, nil
-}
 
-func stringToBigInt(s string) (*big.Int, error) {
-	base := 10
-	if bytes.HasPrefix([]byte(s), []byte("0x")) {
-		base = 16
-		s = strings.TrimPrefix(s, "0x")
-	}
-	n, ok := new(big.Int).SetString(s, base)
-	if !ok {
-		return nil, fmt.Errorf("can not parse string to *big.Int: %s", s)
-	}
-	return n, nil
+	return validation.Errors{"/data/zkproof/pub_signals": err}
 }
@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	stdx509 "crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/iden3/go-iden3-core/v2/w3c"
+	snarkTypes "github.com/iden3/go-rapidsnark/types"
+	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/data/datatest"
+	"github.com/rarimo/passport-identity-provider/internal/service/algorithms"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/internal/service/circuits"
+	"github.com/rarimo/passport-identity-provider/internal/service/cms"
+	"github.com/rarimo/passport-identity-provider/internal/service/crl"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuance"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/internal/service/policy"
+	"github.com/rarimo/passport-identity-provider/internal/service/sod"
+	"github.com/rarimo/passport-identity-provider/internal/service/vault"
+	"github.com/rarimo/passport-identity-provider/internal/service/verifierstate"
+	"github.com/rarimo/passport-identity-provider/resources"
+)
+
+const testCredentialSchema = "https://example.com/schemas/voting.json"
+
+var (
+	oidContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidSHA256      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+)
+
+// acceptingVerifier accepts every proof, the backends are tested against the real keys
+// in the groth16 package.
+type acceptingVerifier struct{}
+
+func (acceptingVerifier) Verify(*circuits.Circuit, snarkTypes.ZKProof) error {
+	return nil
+}
+
+// testDocument is the passport signed by the document signer of the test CSCA.
+type testDocument struct {
+	cscaPEM    []byte
+	sod        requests.DocumentSOD
+	pubSignals []string
+}
+
+func newTestDocument(t *testing.T, now time.Time) testDocument {
+	t.Helper()
+
+	cscaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cscaTemplate := &stdx509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Country: []string{"UA"}, CommonName: "Test CSCA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(10, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              stdx509.KeyUsageCertSign | stdx509.KeyUsageCRLSign,
+	}
+	rawCSCA, err := stdx509.CreateCertificate(rand.Reader, cscaTemplate, cscaTemplate, &cscaKey.PublicKey, cscaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csca, err := stdx509.ParseCertificate(rawCSCA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dsKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawDS, err := stdx509.CreateCertificate(rand.Reader, &stdx509.Certificate{
+		SerialNumber: big.NewInt(0x1b),
+		Subject:      pkix.Name{Country: []string{"UA"}, CommonName: "Test DS"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(1, 0, 0),
+		KeyUsage:     stdx509.KeyUsageDigitalSignature,
+	}, csca, &dsKey.PublicKey, cscaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the proof carries the DG1 hash halves as integers, so they must not start with zero bytes
+	var dg1Hash [sha256.Size]byte
+	for i := 0; dg1Hash[0] == 0 || dg1Hash[sha256.Size/2] == 0; i++ {
+		dg1Hash = sha256.Sum256([]byte(fmt.Sprintf("P<UKRDOE<<JOHN<<<<<<<<<<<<<<<<<<<<<<<<<<<<<<%d", i)))
+	}
+	dg2Hash := sha256.Sum256([]byte("DG2 facial image"))
+
+	encapsulatedContent, err := asn1.Marshal(resources.LDSSecurityObject{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+		DataGroupHashValues: []resources.DataGroupHash{
+			{DataGroupNumber: sod.DG1, DataGroupHashValue: dg1Hash[:]},
+			{DataGroupNumber: sod.DG2, DataGroupHashValue: dg2Hash[:]},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contentDigest := sha256.Sum256(encapsulatedContent)
+	signedAttributes, err := asn1.MarshalWithParams([]testAttribute{
+		newTestAttribute(t, oidContentType, sod.OIDLDSSecurityObject),
+		newTestAttribute(t, cms.OIDMessageDigest, contentDigest[:]),
+	}, "set")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedDigest := sha256.Sum256(signedAttributes)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, dsKey, crypto.SHA256, signedDigest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiry := now.AddDate(5, 0, 0)
+	pubSignals := []string{
+		new(big.Int).SetBytes(dg1Hash[:sha256.Size/2]).String(),
+		new(big.Int).SetBytes(dg1Hash[sha256.Size/2:]).String(),
+		"1",
+		fmt.Sprint(now.Year() % 100), fmt.Sprint(int(now.Month())), fmt.Sprint(now.Day()),
+		fmt.Sprint(expiry.Year() % 100), fmt.Sprint(int(expiry.Month())), fmt.Sprint(expiry.Day()),
+		"30",
+	}
+
+	return testDocument{
+		cscaPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rawCSCA}),
+		sod: requests.DocumentSOD{
+			SignedAttributes:    hex.EncodeToString(signedAttributes),
+			Algorithm:           algorithms.SHA256withRSA,
+			Signature:           hex.EncodeToString(signature),
+			PemFile:             string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rawDS})),
+			EncapsulatedContent: hex.EncodeToString(encapsulatedContent),
+		},
+		pubSignals: pubSignals,
+	}
+}
+
+type testAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+func newTestAttribute(t *testing.T, oid asn1.ObjectIdentifier, value interface{}) testAttribute {
+	t.Helper()
+
+	raw, err := asn1.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testAttribute{Type: oid, Values: []asn1.RawValue{{FullBytes: raw}}}
+}
+
+// newTestVault serves the verifier secret with the blinder as the Vault KV v2 engine does.
+func newTestVault(t *testing.T, blinder string) *vault.VaultClient {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/verifier" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, `{"data":{"data":{"blinder":%q},"metadata":{"version":1}}}`, blinder)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := vault.NewVaultClient(&config.VaultConfig{Address: server.URL, MountPath: "secret", Token: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+// withURLParam sets the URL parameter the chi router would.
+func withURLParam(key, value string) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add(key, value)
+		return context.WithValue(ctx, chi.RouteCtxKey, routeCtx)
+	}
+}
+
+func TestCreateIdentityIssuesClaim(t *testing.T) {
+	now := time.Now().UTC()
+	document := newTestDocument(t, now)
+	log := logan.New()
+
+	masterCertsPath := filepath.Join(t.TempDir(), "masters.pem")
+	if err := os.WriteFile(masterCertsPath, document.cscaPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	verifierConfig := &config.VerifierConfig{
+		VerificationKeysPaths: map[string]string{
+			"sha256": filepath.Join("..", "..", "..", "..", "sha256_verification_key.json"),
+		},
+		MasterCertsPath:      masterCertsPath,
+		AllowedAge:           18,
+		CurrentDateWindow:    24 * time.Hour,
+		ExpiryGraceWindow:    24 * time.Hour,
+		ActiveAuthentication: config.ActiveAuthenticationOptional,
+	}
+	algorithmsRegistry := algorithms.NewDefaultRegistry()
+	verifierState, err := verifierstate.NewReloader(log, verifierConfig, algorithmsRegistry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies, err := policy.NewRegistry(&config.PoliciesConfig{}, verifierConfig.AllowedAge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	did, err := w3c.ParseDID(testIssuerDID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := issuer.NewMemory(&config.IssuerConfig{DID: did, ClaimType: "VotingCredential", CredentialSchema: testCredentialSchema})
+	blinders := newTestVault(t, "42")
+	db := datatest.New()
+
+	ctxs := []func(context.Context) context.Context{
+		CtxMasterQ(db.MasterQ()),
+		CtxVerifierConfig(verifierConfig),
+		CtxIssuer(iss),
+		CtxVaultClient(blinders),
+		CtxAlgorithms(algorithmsRegistry),
+		CtxVerifierState(verifierState),
+		CtxCRLChecker(crl.NewChecker(log, &config.CRLConfig{}, verifierState)),
+		CtxGroth16Verifier(acceptingVerifier{}),
+		// the shipped config, the circuits of the default schema do not commit to the challenge
+		CtxChallengeConfig(config.NewChallengeConfiger(kv.NewViperFile(filepath.Join("..", "..", "..", "..", "config.yaml"))).ChallengeConfig()),
+		CtxPolicies(policies),
+	}
+
+	userID, userAddress := uuid.New(), common.HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	body, err := json.Marshal(requests.CreateIdentityRequest{
+		Data: requests.CreateIdentityRequestData{
+			ID: did,
+			ZKProof: requests.ZKProof{ZKProof: snarkTypes.ZKProof{
+				Proof:      &snarkTypes.ProofData{Protocol: "groth16"},
+				PubSignals: document.pubSignals,
+			}},
+			UserID:      userID,
+			UserAddress: userAddress,
+			DocumentSOD: &document.sod,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	CreateIdentity(w, newTestRequest(http.MethodPost, "/v1/create-identity", body, ctxs...))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body)
+	}
+	var accepted resources.ClaimRequestResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &accepted); err != nil {
+		t.Fatal(err)
+	}
+	if accepted.Data.Attributes.Status != data.ClaimRequestPending {
+		t.Fatalf("expected pending claim request, got %s", accepted.Data.Attributes.Status)
+	}
+
+	// the same statement is not accepted twice
+	w = httptest.NewRecorder()
+	CreateIdentity(w, newTestRequest(http.MethodPost, "/v1/create-identity", body, ctxs...))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on the resubmission, got %d: %s", w.Code, w.Body)
+	}
+
+	worker := issuance.NewWorker(log, &config.IssuanceConfig{
+		PollPeriod:  10 * time.Millisecond,
+		MaxAttempts: 3,
+		MinBackoff:  time.Second,
+		MaxBackoff:  time.Second,
+		Lease:       time.Minute,
+	}, db.MasterQ(), iss, blinders)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(stopped)
+	}()
+
+	var claimRequest resources.ClaimRequestResponse
+	for deadline := time.Now().Add(5 * time.Second); claimRequest.Data.Attributes.Status != data.ClaimRequestIssued; {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatalf("claim request is not issued, got %+v", claimRequest.Data.Attributes)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		w := httptest.NewRecorder()
+		GetClaimRequest(w, newTestRequest(http.MethodGet, "/v1/claim-requests/"+accepted.Data.ID, nil,
+			append(ctxs, withURLParam("id", accepted.Data.ID))...))
+		if err := json.Unmarshal(w.Body.Bytes(), &claimRequest); err != nil {
+			cancel()
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	<-stopped
+
+	claimID := uuid.MustParse(*claimRequest.Data.Attributes.ClaimId)
+	claim, ok := db.Claims[claimID]
+	if !ok {
+		t.Fatal("claim is not recorded")
+	}
+	if claim.UserID != userID || claim.DSSerial != "1b" || claim.DSCountry != "UA" {
+		t.Errorf("unexpected claim %+v", claim)
+	}
+
+	cred, err := iss.GetCredential(claimID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.SchemaType != "VotingCredential" || cred.SchemaUrl != testCredentialSchema || cred.UserID != testIssuerDID {
+		t.Errorf("unexpected credential schema %s %s of %s", cred.SchemaType, cred.SchemaUrl, cred.UserID)
+	}
+	if len(cred.ProofTypes) != 2 || cred.CredentialStatus.RevocationNonce == 0 {
+		t.Errorf("unexpected credential proofs %v and status %+v", cred.ProofTypes, cred.CredentialStatus)
+	}
+	expiry := now.AddDate(5, 0, 0)
+	if want := time.Date(expiry.Year(), expiry.Month(), expiry.Day()+1, 0, 0, 0, 0, time.UTC); !cred.ExpiresAt.Equal(want) {
+		t.Errorf("expected credential expiring at the end of the document expiry date %s, got %s", want, cred.ExpiresAt)
+	}
+
+	var rawSubject map[string]json.RawMessage
+	if err := json.Unmarshal(cred.CredentialSubject, &rawSubject); err != nil {
+		t.Fatal(err)
+	}
+	fields := make([]string, 0, len(rawSubject))
+	for field := range rawSubject {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	if got, want := fmt.Sprint(fields), "[credentialHash documentNullifier f id isAdult issuingAuthority metadata pk userid]"; got != want {
+		t.Errorf("expected credential subject fields %s, got %s", want, got)
+	}
+
+	var subject issuer.CredentialSubject
+	if err := json.Unmarshal(cred.CredentialSubject, &subject); err != nil {
+		t.Fatal(err)
+	}
+	if subject.ID != testIssuerDID || !subject.IsAdult || subject.IssuingAuthority != 1 ||
+		subject.UserID != userID.String() || subject.UserAddress != userAddress.String() ||
+		subject.Features != claim.DocumentHash || subject.DocumentNullifier == nil || subject.CredentialHash == nil {
+		t.Errorf("unexpected credential subject %+v", subject)
+	}
+
+	getClaimStatus := func() string {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		GetClaim(w, newTestRequest(http.MethodGet, "/v1/claims/"+claimID.String(), nil,
+			append(ctxs, withURLParam("id", claimID.String()))...))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+		}
+
+		var response resources.ClaimResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+
+		return response.Data.Attributes.Status
+	}
+
+	if status := getClaimStatus(); status != ClaimStatusActive {
+		t.Fatalf("expected active claim, got %s", status)
+	}
+
+	if err := iss.RevokeClaim(cred.CredentialStatus.RevocationNonce); err != nil {
+		t.Fatal(err)
+	}
+	if status := getClaimStatus(); status != ClaimStatusRevoked {
+		t.Fatalf("expected revoked claim, got %s", status)
+	}
+}
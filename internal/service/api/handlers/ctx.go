@@ -6,8 +6,12 @@ import (
 	stateabi "github.com/iden3/contracts-abi/state/go/abi"
 	"github.com/rarimo/passport-identity-provider/internal/config"
 	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/algorithms"
+	"github.com/rarimo/passport-identity-provider/internal/service/crl"
 	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/internal/service/policy"
 	"github.com/rarimo/passport-identity-provider/internal/service/vault"
+	"github.com/rarimo/passport-identity-provider/internal/service/verifierstate"
 	"gitlab.com/distributed_lab/logan/v3"
 	"net/http"
 )
@@ -22,6 +26,13 @@ const (
 	issuerCtxKey
 	vaultClientCtxKey
 	ethClientCtxKey
+	algorithmsCtxKey
+	verifierStateCtxKey
+	crlCheckerCtxKey
+	groth16VerifierCtxKey
+	challengeConfigCtxKey
+	policiesCtxKey
+	operatorCtxKey
 )
 
 func CtxLog(entry *logan.Entry) func(context.Context) context.Context {
@@ -64,14 +75,14 @@ func StateContract(r *http.Request) *stateabi.State {
 	return r.Context().Value(stateContractKey).(*stateabi.State)
 }
 
-func CtxIssuer(iss *issuer.Issuer) func(context.Context) context.Context {
+func CtxIssuer(iss issuer.Issuer) func(context.Context) context.Context {
 	return func(ctx context.Context) context.Context {
 		return context.WithValue(ctx, issuerCtxKey, iss)
 	}
 }
 
-func Issuer(r *http.Request) *issuer.Issuer {
-	return r.Context().Value(issuerCtxKey).(*issuer.Issuer)
+func Issuer(r *http.Request) issuer.Issuer {
+	return r.Context().Value(issuerCtxKey).(issuer.Issuer)
 }
 
 func CtxVaultClient(vaultClient *vault.VaultClient) func(context.Context) context.Context {
@@ -93,3 +104,80 @@ func CtxEthClient(client *ethclient.Client) func(context.Context) context.Contex
 func EthClient(r *http.Request) *ethclient.Client {
 	return r.Context().Value(ethClientCtxKey).(*ethclient.Client)
 }
+
+func CtxAlgorithms(registry *algorithms.Registry) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, algorithmsCtxKey, registry)
+	}
+}
+
+func Algorithms(r *http.Request) *algorithms.Registry {
+	return r.Context().Value(algorithmsCtxKey).(*algorithms.Registry)
+}
+
+func CtxVerifierState(reloader *verifierstate.Reloader) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, verifierStateCtxKey, reloader)
+	}
+}
+
+func VerifierStateReloader(r *http.Request) *verifierstate.Reloader {
+	return r.Context().Value(verifierStateCtxKey).(*verifierstate.Reloader)
+}
+
+// VerifierState returns the current verification keys and trust anchors, the request
+// must keep using the same State even if it gets reloaded meanwhile.
+func VerifierState(r *http.Request) *verifierstate.State {
+	return VerifierStateReloader(r).Current()
+}
+
+func CtxCRLChecker(checker *crl.Checker) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, crlCheckerCtxKey, checker)
+	}
+}
+
+func CRLChecker(r *http.Request) *crl.Checker {
+	return r.Context().Value(crlCheckerCtxKey).(*crl.Checker)
+}
+
+func CtxGroth16Verifier(groth16Verifier ProofVerifier) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, groth16VerifierCtxKey, groth16Verifier)
+	}
+}
+
+func Groth16Verifier(r *http.Request) ProofVerifier {
+	return r.Context().Value(groth16VerifierCtxKey).(ProofVerifier)
+}
+
+func CtxChallengeConfig(cfg *config.ChallengeConfig) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, challengeConfigCtxKey, cfg)
+	}
+}
+
+func ChallengeConfig(r *http.Request) *config.ChallengeConfig {
+	return r.Context().Value(challengeConfigCtxKey).(*config.ChallengeConfig)
+}
+
+func CtxPolicies(registry *policy.Registry) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, policiesCtxKey, registry)
+	}
+}
+
+func Policies(r *http.Request) *policy.Registry {
+	return r.Context().Value(policiesCtxKey).(*policy.Registry)
+}
+
+// CtxOperator sets the operator the admin request is authenticated as.
+func CtxOperator(operator string) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, operatorCtxKey, operator)
+	}
+}
+
+func Operator(r *http.Request) string {
+	return r.Context().Value(operatorCtxKey).(string)
+}
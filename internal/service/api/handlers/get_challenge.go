@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/iden3/go-iden3-core/v2/w3c"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/activeauth"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/internal/service/certificates"
+	"github.com/rarimo/passport-identity-provider/internal/service/chipauth"
+	"github.com/rarimo/passport-identity-provider/resources"
+)
+
+// challengeNonceSize keeps the nonce below the BN254 scalar field modulus
+const challengeNonceSize = 31
+
+func GetChallenge(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewGetChallengeRequest(r)
+	if err != nil {
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	userDID, err := w3c.ParseDID(req.UserDID)
+	if err != nil {
+		Log(r).WithError(err).Error("failed to parse user DID")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	// the route is public, so the outstanding challenges of a user are capped instead
+	userAddress := common.HexToAddress(req.UserAddress)
+	now := time.Now().UTC()
+	for _, challengesQ := range []data.ChallengeQ{
+		MasterQ(r).Challenge().FilterBy("user_did", userDID.String()),
+		MasterQ(r).Challenge().FilterBy("user_address", userAddress),
+	} {
+		outstanding, err := challengesQ.FilterActive(now).Count()
+		if err != nil {
+			Log(r).WithError(err).Error("failed to count outstanding challenges")
+			ape.RenderErr(w, problems.InternalError())
+			return
+		}
+		if outstanding >= ChallengeConfig(r).MaxOutstanding {
+			ape.RenderErr(w, problems.TooManyRequests())
+			return
+		}
+	}
+
+	nonce := make([]byte, challengeNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		Log(r).WithError(err).Error("failed to generate challenge nonce")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	var terminalKey *chipauth.TerminalKey
+	if req.CACurve != "" {
+		curve, err := certificates.CurveByName(req.CACurve)
+		if err != nil {
+			ape.RenderErr(w, problems.BadRequest(validation.Errors{
+				"/ca_curve": err,
+			})...)
+			return
+		}
+
+		if terminalKey, err = chipauth.GenerateTerminalKey(curve); err != nil {
+			Log(r).WithError(err).Error("failed to generate chip authentication terminal key")
+			ape.RenderErr(w, problems.InternalError())
+			return
+		}
+	}
+
+	nonceInt := new(big.Int).SetBytes(nonce)
+	challenge := data.Challenge{
+		Nonce:       nonceInt.String(),
+		UserDID:     userDID.String(),
+		UserAddress: userAddress,
+		ExpiresAt:   now.Add(ChallengeConfig(r).TTL),
+	}
+
+	attributes := resources.ChallengeAttributes{
+		Nonce:       challenge.Nonce,
+		AaChallenge: hex.EncodeToString(activeauth.Challenge(nonceInt)),
+		ExpiresAt:   challenge.ExpiresAt,
+	}
+	if terminalKey != nil {
+		curveName, privateKey := req.CACurve, hex.EncodeToString(terminalKey.D.Bytes())
+		challenge.CATerminalCurve, challenge.CATerminalKey = &curveName, &privateKey
+
+		publicKey := hex.EncodeToString(terminalKey.PublicKey())
+		attributes.CaTerminalPublicKey = &publicKey
+	}
+
+	if err := MasterQ(r).Challenge().Insert(challenge); err != nil {
+		Log(r).WithError(err).Error("failed to insert challenge")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	ape.Render(w, resources.ChallengeResponse{
+		Data: resources.Challenge{
+			Key: resources.Key{
+				ID:   challenge.Nonce,
+				Type: resources.CHALLENGES,
+			},
+			Attributes: attributes,
+		},
+		Included: resources.Included{},
+	})
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data/datatest"
+)
+
+func TestGetChallengeCapsOutstanding(t *testing.T) {
+	db := datatest.New()
+	cfg := &config.ChallengeConfig{TTL: time.Minute, MaxOutstanding: 2}
+
+	getChallenge := func(userAddress common.Address) int {
+		query := url.Values{"user_did": {testIssuerDID}, "user_address": {userAddress.Hex()}}
+		w := httptest.NewRecorder()
+		GetChallenge(w, newTestRequest(http.MethodGet, "/v1/challenge?"+query.Encode(), nil,
+			CtxMasterQ(db.MasterQ()), CtxChallengeConfig(cfg)))
+		return w.Code
+	}
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if code := getChallenge(common.HexToAddress("0x1")); code != want {
+			t.Fatalf("challenge %d: expected %d, got %d", i+1, want, code)
+		}
+	}
+
+	// the DID is capped whatever the address
+	if code := getChallenge(common.HexToAddress("0x2")); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the DID capped, got %d", code)
+	}
+
+	for nonce, challenge := range db.Challenges {
+		challenge.ExpiresAt = time.Now().Add(-time.Second)
+		db.Challenges[nonce] = challenge
+	}
+	if code := getChallenge(common.HexToAddress("0x1")); code != http.StatusOK {
+		t.Fatalf("expected the expired challenges not counted, got %d", code)
+	}
+	if len(db.Challenges) != 3 {
+		t.Fatalf("expected the expired challenges kept for the cleaner, got %d challenges", len(db.Challenges))
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/resources"
+)
+
+func GetClaimRequest(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewGetClaimRequestRequest(r)
+	if err != nil {
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	claimRequest, err := MasterQ(r).ClaimRequest().FilterBy("id", req.ID).Get()
+	if err != nil {
+		Log(r).WithError(err).Error("failed to get claim request")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+	if claimRequest == nil {
+		ape.RenderErr(w, problems.NotFound())
+		return
+	}
+
+	ape.Render(w, newClaimRequestResponse(*claimRequest))
+}
+
+func newClaimRequestResponse(claimRequest data.ClaimRequest) resources.ClaimRequestResponse {
+	attributes := resources.ClaimRequestAttributes{
+		AntiCloningMechanisms: antiCloningMechanisms(claimRequest.ActiveAuthentication, claimRequest.ChipAuthentication),
+		Attempts:              int64(claimRequest.Attempts),
+		CreatedAt:             claimRequest.CreatedAt,
+		Policy:                claimRequest.Policy,
+		Status:                claimRequest.Status,
+		UpdatedAt:             claimRequest.UpdatedAt,
+	}
+	// the claim ID is recorded before the claim is, it is not reported until then
+	if claimRequest.Status == data.ClaimRequestIssued {
+		claimID := claimRequest.ClaimID.String()
+		attributes.ClaimId = &claimID
+		attributes.IssuerDid = claimRequest.IssuerDID
+	}
+
+	return resources.ClaimRequestResponse{
+		Data: resources.ClaimRequest{
+			Key: resources.Key{
+				ID:   claimRequest.ID.String(),
+				Type: resources.CLAIM_REQUESTS,
+			},
+			Attributes: attributes,
+		},
+		Included: resources.Included{},
+	}
+}
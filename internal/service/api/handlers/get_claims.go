@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/resources"
+)
+
+// Claim credential statuses
+const (
+	ClaimStatusActive     = "active"
+	ClaimStatusExpired    = "expired"
+	ClaimStatusRevoked    = "revoked"
+	ClaimStatusSuperseded = "superseded"
+)
+
+func GetClaim(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewGetClaimRequest(r)
+	if err != nil {
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	claim, err := MasterQ(r).Claim().FilterBy("id", req.ID).Get()
+	if err != nil {
+		Log(r).WithError(err).Error("failed to get claim")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+	if claim == nil {
+		ape.RenderErr(w, problems.NotFound())
+		return
+	}
+
+	cred, err := Issuer(r).GetCredential(claim.ID)
+	if err != nil {
+		Log(r).WithError(err).WithField("claim_id", claim.ID).Error("failed to get claim credential")
+		renderIssuerErr(w, err)
+		return
+	}
+
+	ape.Render(w, resources.ClaimResponse{
+		Data:     newClaimResource(*claim, &cred),
+		Included: resources.Included{},
+	})
+}
+
+// GetClaims lists the claims of the caller with the credential statuses recorded by the service,
+// so the list does not ask the issuer for every claim. The caller proves it is the user by
+// signing the nonce of the challenge issued to the user DID and address with the address key,
+// the claims issued to both are listed. The challenge is not consumed, so the pages are listed
+// with the same one until it expires.
+func GetClaims(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewGetClaimsRequest(r)
+	if err != nil {
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	challenge, err := MasterQ(r).Challenge().FilterBy("nonce", req.Challenge).Get()
+	if err != nil {
+		Log(r).WithError(err).Error("failed to get challenge")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+	if challenge == nil || !time.Now().UTC().Before(challenge.ExpiresAt) {
+		ape.RenderErr(w, problems.Unauthorized())
+		return
+	}
+
+	if err := verifyChallengeSignature(*challenge, req.SignatureBytes()); err != nil {
+		Log(r).WithError(err).WithField("user_address", challenge.UserAddress).Debug("invalid challenge signature")
+		ape.RenderErr(w, problems.Unauthorized())
+		return
+	}
+
+	claims, err := MasterQ(r).Claim().
+		FilterBy("user_did", challenge.UserDID).
+		FilterBy("user_address", challenge.UserAddress).
+		Page(&req.OffsetPageParams).
+		Select()
+	if err != nil {
+		Log(r).WithError(err).Error("failed to select claims")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	response := resources.ClaimListResponse{
+		Data:     make([]resources.Claim, 0, len(claims)),
+		Included: resources.Included{},
+		Links:    offsetLinks(r, req.OffsetPageParams, len(claims)),
+	}
+	for _, claim := range claims {
+		response.Data = append(response.Data, newClaimResource(claim, nil))
+	}
+
+	ape.Render(w, response)
+}
+
+// verifyChallengeSignature checks the challenge nonce is signed by the challenge user address
+// with personal_sign, the recovery ID may be 0/1 or 27/28.
+func verifyChallengeSignature(challenge data.Challenge, signature []byte) error {
+	signature = append([]byte{}, signature...)
+	if signature[crypto.RecoveryIDOffset] >= 27 {
+		signature[crypto.RecoveryIDOffset] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(accounts.TextHash([]byte(challenge.Nonce)), signature)
+	if err != nil {
+		return errors.Wrap(err, "failed to recover public key")
+	}
+	if crypto.PubkeyToAddress(*publicKey) != challenge.UserAddress {
+		return errors.New("challenge is not signed by the user address")
+	}
+
+	return nil
+}
+
+// newClaimResource combines the claim with the status of its credential from the issuer,
+// without the credential the status recorded with the claim is reported.
+func newClaimResource(claim data.Claim, cred *issuer.GetCredentialResponse) resources.Claim {
+	attributes := resources.ClaimAttributes{
+		AntiCloningMechanisms: antiCloningMechanisms(claim.ActiveAuthentication, claim.ChipAuthentication),
+		ClaimId:               claim.ID.String(),
+		IssuerDid:             claim.IssuerDID,
+		Policy:                claim.Policy,
+		UserDid:               claim.UserDID,
+		UserId:                claim.UserID.String(),
+	}
+
+	var revoked, expired bool
+	if cred != nil {
+		revoked, expired = cred.Revoked, cred.Expired
+		attributes.CreatedAt = &cred.CreatedAt
+		attributes.RevocationNonce = &cred.CredentialStatus.RevocationNonce
+		if !cred.ExpiresAt.IsZero() {
+			attributes.ExpiresAt = &cred.ExpiresAt
+		}
+	} else {
+		revoked = claim.Status != data.ClaimActive
+		expired = claim.ExpiresAt != nil && !claim.ExpiresAt.After(time.Now())
+		attributes.CreatedAt = &claim.CreatedAt
+		attributes.RevocationNonce = claim.RevocationNonce
+		attributes.ExpiresAt = claim.ExpiresAt
+	}
+	attributes.Revoked, attributes.Expired = &revoked, &expired
+
+	switch {
+	case revoked && claim.Status == data.ClaimSuperseded:
+		attributes.Status = ClaimStatusSuperseded
+	case revoked:
+		attributes.Status = ClaimStatusRevoked
+	case expired:
+		attributes.Status = ClaimStatusExpired
+	default:
+		attributes.Status = ClaimStatusActive
+	}
+
+	return resources.Claim{
+		Key: resources.Key{
+			ID:   claim.ID.String(),
+			Type: resources.CLAIMS,
+		},
+		Attributes: attributes,
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/iden3/go-iden3-core/v2/w3c"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/data/datatest"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/resources"
+)
+
+const testIssuerDID = "did:iden3:readonly:tJWarsbwqiUxHm8BPi4aYSnnj54AbuR4D2RrhkykQ"
+
+func newTestIssuer(t *testing.T) *issuer.MemoryIssuer {
+	t.Helper()
+
+	did, err := w3c.ParseDID(testIssuerDID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return issuer.NewMemory(&config.IssuerConfig{DID: did, ClaimType: "VotingCredential"})
+}
+
+// newTestRequest returns the request with the handlers context set up.
+func newTestRequest(method, target string, body []byte, ctxs ...func(context.Context) context.Context) *http.Request {
+	r := httptest.NewRequest(method, target, bytes.NewReader(body))
+
+	ctx := CtxLog(logan.New())(r.Context())
+	for _, extend := range ctxs {
+		ctx = extend(ctx)
+	}
+
+	return r.WithContext(ctx)
+}
+
+func TestGetClaims(t *testing.T) {
+	db := datatest.New()
+	iss := newTestIssuer(t)
+	userID := uuid.New()
+
+	userKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userAddress := crypto.PubkeyToAddress(userKey.PublicKey)
+
+	now := time.Now().UTC()
+	claimIDs := make([]uuid.UUID, 3)
+	for i := range claimIDs {
+		rawClaimID, err := iss.IssueVotingClaim(testIssuerDID, 0, true, nil, []byte{1}, big.NewInt(1), userAddress, userID, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		claimIDs[i] = uuid.MustParse(rawClaimID)
+
+		nonce := int64(i + 1)
+		db.Claims[claimIDs[i]] = data.Claim{
+			ID:              claimIDs[i],
+			UserID:          userID,
+			UserDID:         testIssuerDID,
+			UserAddress:     userAddress,
+			IssuerDID:       testIssuerDID,
+			CreatedAt:       now.Add(time.Duration(i) * time.Second),
+			Status:          data.ClaimActive,
+			RevocationNonce: &nonce,
+		}
+	}
+	superseded := db.Claims[claimIDs[1]]
+	superseded.Status = data.ClaimSuperseded
+	db.Claims[claimIDs[1]] = superseded
+
+	// the claim of the same DID with another address is not listed
+	otherID := uuid.New()
+	db.Claims[otherID] = data.Claim{ID: otherID, UserID: uuid.New(), UserDID: testIssuerDID, CreatedAt: now, Status: data.ClaimActive}
+
+	challenge := data.Challenge{Nonce: "42", UserDID: testIssuerDID, UserAddress: userAddress, ExpiresAt: now.Add(time.Minute)}
+	db.Challenges[challenge.Nonce] = challenge
+
+	sign := func(key *ecdsa.PrivateKey, nonce string) string {
+		signature, err := crypto.Sign(accounts.TextHash([]byte(nonce)), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature[crypto.RecoveryIDOffset] += 27
+		return hexutil.Encode(signature)
+	}
+	signature := sign(userKey, challenge.Nonce)
+
+	getClaims := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		GetClaims(w, newTestRequest(http.MethodGet, "/v1/claims?"+query, nil,
+			CtxMasterQ(db.MasterQ()),
+			// the list does not ask the issuer
+			CtxIssuer(nil),
+		))
+		return w
+	}
+	listClaims := func(query string) resources.ClaimListResponse {
+		t.Helper()
+
+		w := getClaims(query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+		}
+
+		var response resources.ClaimListResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+
+		return response
+	}
+
+	first := listClaims("challenge=42&signature=" + signature + "&page[limit]=2&page[order]=asc")
+	if len(first.Data) != 2 || first.Data[0].ID != claimIDs[0].String() || first.Data[1].ID != claimIDs[1].String() {
+		t.Fatalf("unexpected first page %+v", first.Data)
+	}
+	if first.Links == nil || first.Links.Next == "" {
+		t.Fatal("expected the next page link")
+	}
+
+	if attributes := first.Data[0].Attributes; attributes.Status != ClaimStatusActive || *attributes.RevocationNonce != 1 {
+		t.Errorf("expected active claim with the recorded revocation nonce, got %+v", attributes)
+	}
+	if attributes := first.Data[1].Attributes; attributes.Status != ClaimStatusSuperseded || !*attributes.Revoked {
+		t.Errorf("expected superseded claim, got %+v", attributes)
+	}
+
+	second := listClaims("challenge=42&signature=" + signature + "&page[limit]=2&page[order]=asc&page[number]=1")
+	if len(second.Data) != 1 || second.Data[0].ID != claimIDs[2].String() {
+		t.Fatalf("unexpected second page %+v", second.Data)
+	}
+	if second.Links == nil || second.Links.Next != "" {
+		t.Fatal("expected no next page link on the last page")
+	}
+
+	if w := getClaims("challenge=42&signature=" + signature + "&page[limit]=1000"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 on the page limit over the max, got %d", w.Code)
+	}
+
+	otherKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unauthorized := map[string]string{
+		"other key":     "challenge=42&signature=" + sign(otherKey, challenge.Nonce),
+		"other nonce":   "challenge=42&signature=" + sign(userKey, "43"),
+		"unknown nonce": "challenge=43&signature=" + sign(userKey, "43"),
+	}
+	for name, query := range unauthorized {
+		if w := getClaims(query); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", name, w.Code)
+		}
+	}
+
+	challenge.ExpiresAt = now.Add(-time.Second)
+	db.Challenges[challenge.Nonce] = challenge
+	if w := getClaims("challenge=42&signature=" + signature); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with the expired challenge, got %d", w.Code)
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"gitlab.com/distributed_lab/kit/pgdb"
+
+	"github.com/rarimo/passport-identity-provider/resources"
+)
+
+// offsetLinks returns the links of the page and, if it is full, of the next one.
+func offsetLinks(r *http.Request, params pgdb.OffsetPageParams, count int) *resources.Links {
+	links := resources.Links{
+		Self: pageLink(r, params, params.PageNumber),
+	}
+	if uint64(count) == params.Limit {
+		links.Next = pageLink(r, params, params.PageNumber+1)
+	}
+
+	return &links
+}
+
+func pageLink(r *http.Request, params pgdb.OffsetPageParams, number uint64) string {
+	query := r.URL.Query()
+	query.Set("page[limit]", fmt.Sprint(params.Limit))
+	query.Set("page[order]", params.Order)
+	query.Set("page[number]", fmt.Sprint(number))
+
+	return r.URL.Path + "?" + query.Encode()
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/jsonapi"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+)
+
+// serviceUnavailable is rendered when a dependency of the service is down, e.g. the issuer node.
+func serviceUnavailable() *jsonapi.ErrorObject {
+	return &jsonapi.ErrorObject{
+		Title:  http.StatusText(http.StatusServiceUnavailable),
+		Status: fmt.Sprintf("%d", http.StatusServiceUnavailable),
+	}
+}
+
+// renderIssuerErr renders 503 if the issuer node is unavailable, 500 otherwise.
+func renderIssuerErr(w http.ResponseWriter, err error) {
+	if issuer.Unavailable(err) {
+		ape.RenderErr(w, serviceUnavailable())
+		return
+	}
+
+	ape.RenderErr(w, problems.InternalError())
+}
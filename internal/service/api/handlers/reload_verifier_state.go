@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/verifierstate"
+)
+
+// ReloadVerifierState reloads the verification keys and trust anchors from disk,
+// the current ones are kept if the new ones are invalid.
+func ReloadVerifierState(w http.ResponseWriter, r *http.Request) {
+	if err := VerifierStateReloader(r).Reload(); err != nil {
+		if invalid, ok := errors.Cause(err).(*verifierstate.InvalidStateError); ok {
+			Log(r).WithError(err).Warn("rejected invalid verifier state")
+			ape.RenderErr(w, problems.BadRequest(validation.Errors{
+				"verifier_state": invalid,
+			})...)
+			return
+		}
+
+		Log(r).WithError(err).Error("failed to reload verifier state")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/internal/service/revocation"
+	"github.com/rarimo/passport-identity-provider/resources"
+)
+
+// RevokeClaims revokes the credentials of the selected claims and records the revocations
+// with the reason and the operator the request is authenticated as.
+func RevokeClaims(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewRevokeClaimsRequest(r)
+	if err != nil {
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	selector, operator := req.Selector(), Operator(r)
+	log := Log(r).WithFields(logan.F{
+		"selector": selector.String(),
+		"reason":   req.Data.Reason,
+		"operator": operator,
+	})
+
+	revocations, err := revocation.NewRevoker(MasterQ(r), Issuer(r)).Revoke(selector, req.Data.Reason, operator)
+	if err != nil {
+		log.WithError(err).WithField("revoked", len(revocations)).Error("failed to revoke claims")
+		renderIssuerErr(w, err)
+		return
+	}
+	log.WithField("revoked", len(revocations)).Info("claims revoked")
+
+	response := resources.RevocationListResponse{
+		Data:     make([]resources.Revocation, 0, len(revocations)),
+		Included: resources.Included{},
+	}
+	for _, revoked := range revocations {
+		response.Data = append(response.Data, newRevocationResource(revoked))
+	}
+
+	ape.Render(w, response)
+}
+
+func newRevocationResource(revoked data.Revocation) resources.Revocation {
+	revocation := resources.Revocation{
+		Key: resources.Key{
+			ID:   revoked.ID.String(),
+			Type: resources.REVOCATIONS,
+		},
+		Attributes: resources.RevocationAttributes{
+			CreatedAt:       revoked.CreatedAt,
+			DocumentHash:    revoked.DocumentHash,
+			Operator:        revoked.Operator,
+			Reason:          revoked.Reason,
+			RevocationNonce: revoked.RevocationNonce,
+			Selector:        revoked.Selector,
+			UserId:          revoked.UserID.String(),
+		},
+	}
+	if revoked.ClaimID != nil {
+		claimID := revoked.ClaimID.String()
+		revocation.Attributes.ClaimId = &claimID
+	}
+	if revoked.ClaimRequestID != nil {
+		claimRequestID := revoked.ClaimRequestID.String()
+		revocation.Attributes.ClaimRequestId = &claimRequestID
+	}
+
+	return revocation
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+
+	snarkTypes "github.com/iden3/go-rapidsnark/types"
+	"github.com/iden3/go-rapidsnark/verifier"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/circuits"
+)
+
+// Groth16 verifier backends
+const (
+	BackendNative     = "native"
+	BackendRapidsnark = "rapidsnark"
+)
+
+// ProofVerifier verifies the registration proofs of the circuits.
+type ProofVerifier interface {
+	Verify(circuit *circuits.Circuit, proof snarkTypes.ZKProof) error
+}
+
+// NativeVerifier verifies the proofs with the keys prepared when the circuits are loaded.
+type NativeVerifier struct{}
+
+func (NativeVerifier) Verify(circuit *circuits.Circuit, proof snarkTypes.ZKProof) error {
+	return circuit.PreparedKey.Verify(proof)
+}
+
+// RapidsnarkVerifier verifies the proofs with go-rapidsnark, it parses the JSON key on every call.
+type RapidsnarkVerifier struct{}
+
+func (RapidsnarkVerifier) Verify(circuit *circuits.Circuit, proof snarkTypes.ZKProof) error {
+	return verifier.VerifyGroth16(proof, circuit.VerificationKey)
+}
+
+// NewGroth16Verifier returns the verifier of the backend, native by default.
+func NewGroth16Verifier(backend string) (ProofVerifier, error) {
+	switch backend {
+	case "", BackendNative:
+		return NativeVerifier{}, nil
+	case BackendRapidsnark:
+		return RapidsnarkVerifier{}, nil
+	default:
+		return nil, fmt.Errorf("unknown Groth16 verifier backend %s", backend)
+	}
+}
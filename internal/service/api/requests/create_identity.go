@@ -5,24 +5,67 @@ import (
 	"net/http"
 
 	"github.com/ethereum/go-ethereum/common"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
 	"github.com/google/uuid"
 	"github.com/iden3/go-iden3-core/v2/w3c"
 	snarkTypes "github.com/iden3/go-rapidsnark/types"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 )
 
+type DocumentSOD struct {
+	SignedAttributes    string `json:"signed_attributes"`
+	Algorithm           string `json:"algorithm"`
+	AlgorithmParameters string `json:"algorithm_parameters,omitempty"`
+	Signature           string `json:"signature"`
+	PemFile             string `json:"pem_file"`
+	EncapsulatedContent string `json:"encapsulated_content"`
+}
+
+// ZKProof is the registration proof along with the circuit it was generated by.
+type ZKProof struct {
+	snarkTypes.ZKProof
+	// CircuitID defaults to the current circuit of the signature algorithm
+	CircuitID string `json:"circuit_id,omitempty"`
+}
+
+// ActiveAuthentication is the chip response to the INTERNAL AUTHENTICATE with the challenge
+// derived from the registration challenge nonce.
+type ActiveAuthentication struct {
+	// DG15 is the hex-encoded DG15 file
+	DG15 string `json:"dg15"`
+	// Signature is the hex-encoded chip signature
+	Signature string `json:"signature"`
+	// Challenge is the registration challenge nonce
+	Challenge string `json:"challenge"`
+	// HashAlgorithm of the ECDSA signature, e.g. SHA-256
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+}
+
+// ChipAuthentication is the chip authentication transcript: the chip agrees the session keys
+// with the terminal ephemeral key the service generated for the registration challenge.
+type ChipAuthentication struct {
+	// DG14 is the hex-encoded DG14 file
+	DG14 string `json:"dg14"`
+	// Response is the hex-encoded secure messaging response APDU to the first command
+	// after chip authentication
+	Response string `json:"response"`
+	// Challenge is the registration challenge nonce
+	Challenge string `json:"challenge"`
+}
+
 type CreateIdentityRequestData struct {
-	ID          *w3c.DID           `json:"id"`
-	ZKProof     snarkTypes.ZKProof `json:"zkproof"`
-	UserID      uuid.UUID          `json:"user_id"`
-	UserAddress common.Address     `json:"user_address"`
-	DocumentSOD struct {
-		SignedAttributes    string `json:"signed_attributes"`
-		Algorithm           string `json:"algorithm"`
-		Signature           string `json:"signature"`
-		PemFile             string `json:"pem_file"`
-		EncapsulatedContent string `json:"encapsulated_content"`
-	} `json:"document_sod"`
+	ID          *w3c.DID       `json:"id"`
+	ZKProof     ZKProof        `json:"zkproof"`
+	UserID      uuid.UUID      `json:"user_id"`
+	UserAddress common.Address `json:"user_address"`
+	DocumentSOD *DocumentSOD   `json:"document_sod,omitempty"`
+	// SOD is the raw hex or base64 encoded EF.SOD, an alternative to the pre-split DocumentSOD
+	SOD                  string                `json:"sod,omitempty"`
+	ActiveAuthentication *ActiveAuthentication `json:"active_authentication,omitempty"`
+	ChipAuthentication   *ChipAuthentication   `json:"chip_authentication,omitempty"`
+	// Policy is the eligibility policy name, the default one if empty
+	Policy string `json:"policy,omitempty"`
 }
 
 type CreateIdentityRequest struct {
@@ -37,5 +80,28 @@ func NewCreateIdentityRequest(r *http.Request) (CreateIdentityRequest, error) {
 		return request, errors.Wrap(err, "failed to unmarshal")
 	}
 
-	return request, nil
+	return request, validateCreateIdentityRequest(request)
+}
+
+func validateCreateIdentityRequest(r CreateIdentityRequest) error {
+	errs := validation.Errors{
+		"/data/document_sod": validation.Validate(r.Data.DocumentSOD,
+			validation.When(r.Data.SOD == "", validation.Required).Else(validation.Nil),
+		),
+		"/data/sod": validation.Validate(r.Data.SOD,
+			validation.When(r.Data.DocumentSOD == nil, validation.Required),
+		),
+	}
+	if aa := r.Data.ActiveAuthentication; aa != nil {
+		errs["/data/active_authentication/dg15"] = validation.Validate(aa.DG15, validation.Required, is.Hexadecimal)
+		errs["/data/active_authentication/signature"] = validation.Validate(aa.Signature, validation.Required, is.Hexadecimal)
+		errs["/data/active_authentication/challenge"] = validation.Validate(aa.Challenge, validation.Required, is.Digit)
+	}
+	if ca := r.Data.ChipAuthentication; ca != nil {
+		errs["/data/chip_authentication/dg14"] = validation.Validate(ca.DG14, validation.Required, is.Hexadecimal)
+		errs["/data/chip_authentication/response"] = validation.Validate(ca.Response, validation.Required, is.Hexadecimal)
+		errs["/data/chip_authentication/challenge"] = validation.Validate(ca.Challenge, validation.Required, is.Digit)
+	}
+
+	return errs.Filter()
 }
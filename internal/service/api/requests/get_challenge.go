@@ -0,0 +1,43 @@
+package requests
+
+import (
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+	"gitlab.com/distributed_lab/urlval"
+)
+
+type GetChallengeRequest struct {
+	UserDID     string `url:"user_did"`
+	UserAddress string `url:"user_address"`
+	// CACurve is the curve of the DG14 chip authentication key, the terminal ephemeral
+	// key is generated on it if set, e.g. brainpoolP256r1
+	CACurve string `url:"ca_curve"`
+}
+
+func NewGetChallengeRequest(r *http.Request) (GetChallengeRequest, error) {
+	var req GetChallengeRequest
+
+	err := urlval.Decode(r.URL.Query(), &req)
+	if err != nil {
+		return GetChallengeRequest{}, errors.Wrap(err, "failed to decode url")
+	}
+
+	return req, validateGetChallengeRequest(req)
+}
+
+func validateGetChallengeRequest(r GetChallengeRequest) error {
+	return validation.Errors{
+		"/user_did": validation.Validate(r.UserDID, validation.Required),
+		"/user_address": validation.Validate(r.UserAddress, validation.Required,
+			validation.By(func(value interface{}) error {
+				if !common.IsHexAddress(value.(string)) {
+					return errors.New("invalid address")
+				}
+				return nil
+			}),
+		),
+	}.Filter()
+}
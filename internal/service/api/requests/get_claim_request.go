@@ -0,0 +1,25 @@
+package requests
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type GetClaimRequestRequest struct {
+	ID uuid.UUID
+}
+
+func NewGetClaimRequestRequest(r *http.Request) (GetClaimRequestRequest, error) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return GetClaimRequestRequest{}, validation.Errors{
+			"/id": errors.Wrap(err, "invalid claim request ID"),
+		}
+	}
+
+	return GetClaimRequestRequest{ID: id}, nil
+}
@@ -0,0 +1,62 @@
+package requests
+
+import (
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"gitlab.com/distributed_lab/kit/pgdb"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+	"gitlab.com/distributed_lab/urlval"
+)
+
+// maxClaimsPageLimit bounds the claims listed per page
+const maxClaimsPageLimit = 100
+
+// signatureSize is the size of the r || s || v Ethereum signature
+const signatureSize = 65
+
+type GetClaimsRequest struct {
+	pgdb.OffsetPageParams
+
+	// Challenge is the nonce of the challenge issued to the user DID and address
+	Challenge string `url:"challenge"`
+	// Signature is the hex-encoded personal_sign signature of the challenge nonce by the
+	// user address
+	Signature string `url:"signature"`
+}
+
+func NewGetClaimsRequest(r *http.Request) (GetClaimsRequest, error) {
+	var req GetClaimsRequest
+
+	err := urlval.Decode(r.URL.Query(), &req)
+	if err != nil {
+		return GetClaimsRequest{}, errors.Wrap(err, "failed to decode url")
+	}
+
+	return req, validateGetClaimsRequest(req)
+}
+
+// SignatureBytes returns the decoded signature, it is valid after the validation.
+func (r GetClaimsRequest) SignatureBytes() []byte {
+	signature, _ := hexutil.Decode(r.Signature)
+	return signature
+}
+
+func validateGetClaimsRequest(r GetClaimsRequest) error {
+	return validation.Errors{
+		"/page/limit": validation.Validate(r.Limit, validation.Max(uint64(maxClaimsPageLimit))),
+		"/page/order": validation.Validate(r.Order, validation.In(pgdb.OrderTypeAsc, pgdb.OrderTypeDesc)),
+		"/challenge":  validation.Validate(r.Challenge, validation.Required),
+		"/signature": validation.Validate(r.Signature, validation.Required, validation.By(func(value interface{}) error {
+			signature, err := hexutil.Decode(value.(string))
+			if err != nil {
+				return errors.Wrap(err, "invalid hex")
+			}
+			if len(signature) != signatureSize {
+				return errors.Errorf("signature must be %d bytes", signatureSize)
+			}
+			return nil
+		})),
+	}.Filter()
+}
@@ -0,0 +1,57 @@
+package requests
+
+import (
+	"encoding/json"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/revocation"
+)
+
+type RevokeClaimsRequestData struct {
+	ClaimID      *uuid.UUID `json:"claim_id,omitempty"`
+	UserID       *uuid.UUID `json:"user_id,omitempty"`
+	DocumentHash *string    `json:"document_hash,omitempty"`
+	// DSSerial is the hex serial number of the document signer certificate
+	DSSerial  *string `json:"ds_serial,omitempty"`
+	DSCountry *string `json:"ds_country,omitempty"`
+	Reason    string  `json:"reason"`
+}
+
+type RevokeClaimsRequest struct {
+	Data RevokeClaimsRequestData `json:"data"`
+}
+
+func NewRevokeClaimsRequest(r *http.Request) (RevokeClaimsRequest, error) {
+	var request RevokeClaimsRequest
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		return request, errors.Wrap(err, "failed to unmarshal")
+	}
+
+	return request, validateRevokeClaimsRequest(request)
+}
+
+// Selector returns the claims selector of the request.
+func (r RevokeClaimsRequest) Selector() revocation.Selector {
+	return revocation.Selector{
+		ClaimID:      r.Data.ClaimID,
+		UserID:       r.Data.UserID,
+		DocumentHash: r.Data.DocumentHash,
+		DSSerial:     r.Data.DSSerial,
+		DSCountry:    r.Data.DSCountry,
+	}
+}
+
+func validateRevokeClaimsRequest(r RevokeClaimsRequest) error {
+	return validation.Errors{
+		"/data": r.Selector().Validate(),
+		"/data/reason": validation.Validate(r.Data.Reason, validation.Required, validation.By(func(value interface{}) error {
+			return revocation.ValidateReason(value.(string))
+		})),
+	}.Filter()
+}
@@ -0,0 +1,138 @@
+package certificates
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/keybase/go-crypto/brainpool"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+var (
+	ErrUnsupportedCurve = errors.New("unsupported elliptic curve")
+)
+
+var (
+	oidPrimeField = asn1.ObjectIdentifier{1, 2, 840, 10045, 1, 1}
+)
+
+type namedCurve struct {
+	oid   asn1.ObjectIdentifier
+	curve func() elliptic.Curve
+}
+
+// namedCurves are the curves the document signers and CSCAs use, see BSI TR-03110 and RFC 5639
+var namedCurves = []namedCurve{
+	{oid: asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}, curve: elliptic.P256},
+	{oid: asn1.ObjectIdentifier{1, 3, 132, 0, 33}, curve: elliptic.P224},
+	{oid: asn1.ObjectIdentifier{1, 3, 132, 0, 34}, curve: elliptic.P384},
+	{oid: asn1.ObjectIdentifier{1, 3, 132, 0, 35}, curve: elliptic.P521},
+	{oid: asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 7}, curve: brainpool.P256r1},
+	{oid: asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 8}, curve: brainpool.P256t1},
+	{oid: asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 11}, curve: brainpool.P384r1},
+	{oid: asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 12}, curve: brainpool.P384t1},
+	{oid: asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 13}, curve: brainpool.P512r1},
+	{oid: asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 14}, curve: brainpool.P512t1},
+}
+
+// specifiedECDomain reflects the explicit EC domain parameters, see RFC 3279, section 2.3.5.
+type specifiedECDomain struct {
+	Version int
+	FieldID struct {
+		FieldType asn1.ObjectIdentifier
+		Prime     *big.Int
+	}
+	Curve struct {
+		A    []byte
+		B    []byte
+		Seed asn1.BitString `asn1:"optional"`
+	}
+	Base     []byte
+	Order    *big.Int
+	Cofactor *big.Int `asn1:"optional"`
+}
+
+// curveFromParameters resolves the curve of the EC public key algorithm parameters,
+// either named by OID or given explicitly.
+func curveFromParameters(parameters asn1.RawValue) (elliptic.Curve, error) {
+	if parameters.Tag == asn1.TagOID {
+		var oid asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(parameters.FullBytes, &oid); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal named curve")
+		}
+
+		for _, named := range namedCurves {
+			if named.oid.Equal(oid) {
+				return named.curve(), nil
+			}
+		}
+
+		return nil, errors.Wrap(ErrUnsupportedCurve, oid.String())
+	}
+
+	domain := specifiedECDomain{}
+	if _, err := asn1.Unmarshal(parameters.FullBytes, &domain); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal explicit EC domain parameters")
+	}
+
+	return curveFromDomain(domain)
+}
+
+// curveFromDomain matches the explicit domain against the known curves, arbitrary
+// domains are not accepted since Go implements the known curves only.
+func curveFromDomain(domain specifiedECDomain) (elliptic.Curve, error) {
+	if !domain.FieldID.FieldType.Equal(oidPrimeField) {
+		return nil, errors.Wrap(ErrUnsupportedCurve, fmt.Sprintf("field type %s", domain.FieldID.FieldType))
+	}
+
+	if len(domain.Base) == 0 || domain.Base[0] != 4 || len(domain.Base)%2 != 1 {
+		return nil, errors.New("base point is not in uncompressed form")
+	}
+
+	coordinateLen := len(domain.Base) / 2
+	gx := new(big.Int).SetBytes(domain.Base[1 : 1+coordinateLen])
+	gy := new(big.Int).SetBytes(domain.Base[1+coordinateLen:])
+
+	for _, named := range namedCurves {
+		params := named.curve().Params()
+
+		if params.P.Cmp(domain.FieldID.Prime) == 0 &&
+			params.N.Cmp(domain.Order) == 0 &&
+			params.Gx.Cmp(gx) == 0 &&
+			params.Gy.Cmp(gy) == 0 {
+			return named.curve(), nil
+		}
+	}
+
+	return nil, errors.Wrap(ErrUnsupportedCurve, fmt.Sprintf("explicit domain with prime %x", domain.FieldID.Prime.Bytes()))
+}
+
+func unmarshalPoint(curve elliptic.Curve, data []byte) (*big.Int, *big.Int, error) {
+	byteLen := (curve.Params().BitSize + 7) / 8
+	if len(data) != 1+2*byteLen || data[0] != 4 {
+		return nil, nil, errors.New("public key point is not in uncompressed form")
+	}
+
+	x := new(big.Int).SetBytes(data[1 : 1+byteLen])
+	y := new(big.Int).SetBytes(data[1+byteLen:])
+
+	params := curve.Params()
+	if x.Cmp(params.P) >= 0 || y.Cmp(params.P) >= 0 || !curve.IsOnCurve(x, y) {
+		return nil, nil, errors.New("public key point is not on the curve")
+	}
+
+	return x, y, nil
+}
+
+// CurveByName returns the supported curve by its name, e.g. P-256 or brainpoolP256r1.
+func CurveByName(name string) (elliptic.Curve, error) {
+	for _, named := range namedCurves {
+		if curve := named.curve(); curve.Params().Name == name {
+			return curve, nil
+		}
+	}
+
+	return nil, errors.Wrap(ErrUnsupportedCurve, name)
+}
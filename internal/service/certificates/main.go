@@ -0,0 +1,223 @@
+package certificates
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	stdx509 "crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/rarimo/certificate-transparency-go/x509"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+var (
+	oidECPublicKey = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+)
+
+// placeholderSPKI is a valid P-256 key put in place of the keys x509 can not parse
+var placeholderSPKI = func() []byte {
+	curve := elliptic.P256()
+	spki, err := stdx509.MarshalPKIXPublicKey(&ecdsa.PublicKey{
+		Curve: curve,
+		X:     curve.Params().Gx,
+		Y:     curve.Params().Gy,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return spki
+}()
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type certificate struct {
+	TBSCertificate     asn1.RawValue
+	SignatureAlgorithm asn1.RawValue
+	SignatureValue     asn1.RawValue
+}
+
+// Parse parses DER-encoded certificate. Unlike x509.ParseCertificate it accepts
+// ECDSA keys on brainpool curves, named or given by explicit domain parameters.
+func Parse(der []byte) (*x509.Certificate, error) {
+	cert, err := x509.ParseCertificate(der)
+	if err == nil || (cert != nil && !x509.IsFatal(err)) {
+		return cert, nil
+	}
+
+	parsed, ecErr := parseWithECKey(der)
+	if ecErr != nil {
+		if errors.Cause(ecErr) == ErrUnsupportedCurve {
+			return nil, ecErr
+		}
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// ParsePEM parses the first PEM-encoded certificate.
+func ParsePEM(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid certificate: invalid PEM")
+	}
+
+	return Parse(block.Bytes)
+}
+
+// ParsePEMBundle parses all the certificates of the PEM bundle. Certificates that fail
+// to parse are skipped and their errors are returned alongside.
+func ParsePEMBundle(data []byte) ([]*x509.Certificate, []error) {
+	certs := make([]*x509.Certificate, 0)
+	errs := make([]error, 0)
+
+	for len(data) > 0 {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" || len(block.Headers) != 0 {
+			continue
+		}
+
+		cert, err := Parse(block.Bytes)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		certs = append(certs, cert)
+	}
+
+	return certs, errs
+}
+
+// ParsePublicKey parses the DER-encoded SubjectPublicKeyInfo, EC keys are accepted on the same
+// curves as by Parse.
+func ParsePublicKey(rawSPKI []byte) (crypto.PublicKey, error) {
+	spki := subjectPublicKeyInfo{}
+	if _, err := asn1.Unmarshal(rawSPKI, &spki); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal subject public key info")
+	}
+
+	if spki.Algorithm.Algorithm.Equal(oidECPublicKey) {
+		return parseECPublicKey(rawSPKI)
+	}
+
+	pubKey, err := stdx509.ParsePKIXPublicKey(rawSPKI)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse public key")
+	}
+
+	return pubKey, nil
+}
+
+// parseWithECKey parses the certificate with the EC key substituted by a placeholder
+// and then puts the actual key and raw contents back.
+func parseWithECKey(der []byte) (*x509.Certificate, error) {
+	rawCert := certificate{}
+	if _, err := asn1.Unmarshal(der, &rawCert); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal certificate")
+	}
+
+	tbsFields := make([]asn1.RawValue, 0)
+	for rest := rawCert.TBSCertificate.Bytes; len(rest) > 0; {
+		var field asn1.RawValue
+
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &field); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal TBS certificate field")
+		}
+
+		tbsFields = append(tbsFields, field)
+	}
+
+	// serialNumber, signature, issuer, validity and subject precede the key,
+	// the version is the optional explicit [0] field in front of them
+	spkiIndex := 5
+	if len(tbsFields) > 0 && tbsFields[0].Class == asn1.ClassContextSpecific && tbsFields[0].Tag == 0 {
+		spkiIndex++
+	}
+	if len(tbsFields) <= spkiIndex {
+		return nil, errors.New("subject public key info is missing")
+	}
+	rawSPKI := tbsFields[spkiIndex].FullBytes
+
+	pubKey, err := parseECPublicKey(rawSPKI)
+	if err != nil {
+		return nil, err
+	}
+
+	var tbs bytes.Buffer
+	for i, field := range tbsFields {
+		if i == spkiIndex {
+			tbs.Write(placeholderSPKI)
+			continue
+		}
+		tbs.Write(field.FullBytes)
+	}
+
+	tbsDER, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSequence, IsCompound: true, Bytes: tbs.Bytes()})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal TBS certificate")
+	}
+
+	certDER, err := asn1.Marshal(asn1.RawValue{
+		Tag:        asn1.TagSequence,
+		IsCompound: true,
+		Bytes:      append(append(tbsDER, rawCert.SignatureAlgorithm.FullBytes...), rawCert.SignatureValue.FullBytes...),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal certificate")
+	}
+
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse certificate")
+	}
+
+	cert.Raw = der
+	cert.RawTBSCertificate = rawCert.TBSCertificate.FullBytes
+	cert.RawSubjectPublicKeyInfo = rawSPKI
+	cert.PublicKeyAlgorithm = x509.ECDSA
+	cert.PublicKey = pubKey
+
+	return cert, nil
+}
+
+func parseECPublicKey(rawSPKI []byte) (*ecdsa.PublicKey, error) {
+	spki := subjectPublicKeyInfo{}
+	if _, err := asn1.Unmarshal(rawSPKI, &spki); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal subject public key info")
+	}
+
+	if !spki.Algorithm.Algorithm.Equal(oidECPublicKey) {
+		return nil, fmt.Errorf("%s is not an EC public key algorithm", spki.Algorithm.Algorithm)
+	}
+
+	curve, err := curveFromParameters(spki.Algorithm.Parameters)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get curve")
+	}
+
+	x, y, err := unmarshalPoint(curve, spki.PublicKey.RightAlign())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal public key")
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     x,
+		Y:     y,
+	}, nil
+}
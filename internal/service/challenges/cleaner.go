@@ -0,0 +1,45 @@
+// Package challenges maintains the registration challenges issued by GET /v1/challenge.
+package challenges
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+)
+
+// Cleaner deletes the expired challenges every cleanup period, so the public challenge
+// route only inserts them.
+type Cleaner struct {
+	log *logan.Entry
+	cfg *config.ChallengeConfig
+	db  data.MasterQ
+}
+
+func NewCleaner(log *logan.Entry, cfg *config.ChallengeConfig, db data.MasterQ) *Cleaner {
+	return &Cleaner{
+		log: log,
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// Run deletes the expired challenges every cleanup period until the context is done.
+func (c *Cleaner) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.CleanupPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.db.New().Challenge().DeleteExpired(time.Now().UTC()); err != nil {
+				c.log.WithError(err).Error("failed to delete expired challenges")
+			}
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package chipauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/certificates"
+)
+
+// Chip authentication protocols of DG14, see BSI TR-03110 part 3, section A.1.1
+var (
+	oidPKECDH = asn1.ObjectIdentifier{0, 4, 0, 127, 0, 7, 2, 2, 1, 2}
+	oidCAECDH = asn1.ObjectIdentifier{0, 4, 0, 127, 0, 7, 2, 2, 3, 2}
+)
+
+// Cipher is the secure messaging cipher agreed by chip authentication.
+type Cipher int
+
+const (
+	Cipher3DES Cipher = iota + 1
+	CipherAES128
+	CipherAES192
+	CipherAES256
+)
+
+func (c Cipher) String() string {
+	switch c {
+	case Cipher3DES:
+		return "3DES"
+	case CipherAES128:
+		return "AES-128"
+	case CipherAES192:
+		return "AES-192"
+	case CipherAES256:
+		return "AES-256"
+	default:
+		return fmt.Sprintf("Cipher(%d)", int(c))
+	}
+}
+
+// Key is the chip authentication public key of DG14 along with the cipher the chip uses with it.
+type Key struct {
+	// KeyID is nil if the chip has a single key
+	KeyID     *big.Int
+	PublicKey *ecdsa.PublicKey
+	Cipher    Cipher
+}
+
+type securityInfo struct {
+	Protocol     asn1.ObjectIdentifier
+	RequiredData asn1.RawValue
+	OptionalData asn1.RawValue `asn1:"optional"`
+}
+
+// ParseDG14 returns the ECDH chip authentication keys of the DG14 file. The cipher of the
+// key is the one of the ChipAuthenticationInfo with the same key ID, 3DES if there is none.
+func ParseDG14(dg14 []byte) ([]Key, error) {
+	var file asn1.RawValue
+	rest, err := asn1.Unmarshal(dg14, &file)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal DG14")
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after DG14")
+	}
+	if file.Class != asn1.ClassApplication || file.Tag != 14 {
+		return nil, fmt.Errorf("expected DG14 tag, got class %d tag %d", file.Class, file.Tag)
+	}
+
+	var infos []securityInfo
+	if _, err := asn1.UnmarshalWithParams(file.Bytes, &infos, "set"); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal security infos")
+	}
+
+	ciphers := make(map[string]Cipher)
+	keys := make([]Key, 0)
+	for _, info := range infos {
+		switch {
+		case isArcOf(info.Protocol, oidCAECDH):
+			cipher, err := caCipher(info.Protocol)
+			if err != nil {
+				return nil, err
+			}
+
+			keyID, err := optionalKeyID(info.OptionalData)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid chip authentication info")
+			}
+			ciphers[keyID.String()] = cipher
+		case info.Protocol.Equal(oidPKECDH):
+			publicKey, err := certificates.ParsePublicKey(info.RequiredData.FullBytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to parse chip authentication public key")
+			}
+
+			ecdsaKey, ok := publicKey.(*ecdsa.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("expected EC chip authentication public key, got %T", publicKey)
+			}
+
+			keyID, err := optionalKeyID(info.OptionalData)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid chip authentication public key info")
+			}
+			keys = append(keys, Key{KeyID: keyID, PublicKey: ecdsaKey})
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("DG14 has no ECDH chip authentication public key")
+	}
+
+	for i := range keys {
+		keys[i].Cipher = Cipher3DES
+		if cipher, ok := ciphers[keys[i].KeyID.String()]; ok {
+			keys[i].Cipher = cipher
+		}
+	}
+
+	return keys, nil
+}
+
+// TerminalKey is the terminal ephemeral key pair of the chip authentication. The service
+// generates it for the registration challenge and hands out the public key only, so the
+// session keys are agreed with it by the chip holding the DG14 private key and nobody else.
+type TerminalKey struct {
+	Curve elliptic.Curve
+	D     *big.Int
+}
+
+// GenerateTerminalKey generates the terminal ephemeral key on the curve of the chip key.
+func GenerateTerminalKey(curve elliptic.Curve) (*TerminalKey, error) {
+	n := new(big.Int).Sub(curve.Params().N, big.NewInt(1))
+	d, err := rand.Int(rand.Reader, n)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate terminal private key")
+	}
+
+	return &TerminalKey{Curve: curve, D: d.Add(d, big.NewInt(1))}, nil
+}
+
+// PublicKey returns the uncompressed terminal public key point the chip is given.
+func (k *TerminalKey) PublicKey() []byte {
+	x, y := k.Curve.ScalarBaseMult(k.D.Bytes())
+	size := (k.Curve.Params().BitSize + 7) / 8
+
+	point := make([]byte, 1+2*size)
+	point[0] = 4
+	x.FillBytes(point[1 : 1+size])
+	y.FillBytes(point[1+size:])
+
+	return point
+}
+
+// Verify checks the chip has agreed the same session keys as the terminal: the response must
+// be the secure messaging response APDU to the first command after chip authentication, its
+// MAC key is derived from the shared secret of the chip private key and the terminal public key,
+// which only the genuine chip and the service holding the terminal private key can compute.
+func Verify(key Key, terminalKey *TerminalKey, response []byte) error {
+	curve := key.PublicKey.Curve
+	if curve.Params().Name != terminalKey.Curve.Params().Name {
+		return fmt.Errorf("terminal key is on %s, the chip key is on %s",
+			terminalKey.Curve.Params().Name, curve.Params().Name)
+	}
+
+	x, _ := curve.ScalarMult(key.PublicKey.X, key.PublicKey.Y, terminalKey.D.Bytes())
+	sharedSecret := x.FillBytes(make([]byte, (curve.Params().BitSize+7)/8))
+
+	macKey, err := deriveKey(sharedSecret, key.Cipher, macKeyCounter)
+	if err != nil {
+		return err
+	}
+
+	return verifyResponseMAC(macKey, key.Cipher, response)
+}
+
+// key derivation counters, see ICAO 9303 part 11, section 9.7.1
+const (
+	macKeyCounter = 2
+)
+
+func deriveKey(sharedSecret []byte, cipher Cipher, counter uint32) ([]byte, error) {
+	var (
+		hash    crypto.Hash
+		keySize int
+	)
+	switch cipher {
+	case Cipher3DES:
+		hash, keySize = crypto.SHA1, 16
+	case CipherAES128:
+		hash, keySize = crypto.SHA1, 16
+	case CipherAES192:
+		hash, keySize = crypto.SHA256, 24
+	case CipherAES256:
+		hash, keySize = crypto.SHA256, 32
+	default:
+		return nil, fmt.Errorf("unsupported cipher %s", cipher)
+	}
+
+	data := binary.BigEndian.AppendUint32(append([]byte{}, sharedSecret...), counter)
+
+	var keyData []byte
+	if hash == crypto.SHA1 {
+		digest := sha1.Sum(data)
+		keyData = digest[:]
+	} else {
+		digest := sha256.Sum256(data)
+		keyData = digest[:]
+	}
+
+	return keyData[:keySize], nil
+}
+
+func caCipher(protocol asn1.ObjectIdentifier) (Cipher, error) {
+	if len(protocol) == len(oidCAECDH)+1 {
+		switch protocol[len(oidCAECDH)] {
+		case 1:
+			return Cipher3DES, nil
+		case 2:
+			return CipherAES128, nil
+		case 3:
+			return CipherAES192, nil
+		case 4:
+			return CipherAES256, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unsupported chip authentication protocol %s", protocol)
+}
+
+// optionalKeyID returns the key ID of the security info, nil if the chip has a single key.
+func optionalKeyID(raw asn1.RawValue) (*big.Int, error) {
+	if len(raw.FullBytes) == 0 {
+		return nil, nil
+	}
+
+	keyID := new(big.Int)
+	if _, err := asn1.Unmarshal(raw.FullBytes, &keyID); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal key ID")
+	}
+
+	return keyID, nil
+}
+
+func isArcOf(oid, prefix asn1.ObjectIdentifier) bool {
+	return len(oid) > len(prefix) && oid[:len(prefix)].Equal(prefix)
+}
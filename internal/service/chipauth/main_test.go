@@ -0,0 +1,91 @@
+package chipauth
+
+import (
+	"crypto/aes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/keybase/go-crypto/brainpool"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/certificates"
+)
+
+// chipResponse is the response APDU the chip protects with the session keys agreed with
+// the terminal public key: the status word data object followed by the MAC one.
+func chipResponse(t *testing.T, chipKey *ecdsa.PrivateKey, cipher Cipher, terminalPublicKey []byte) []byte {
+	t.Helper()
+
+	x, y := elliptic.Unmarshal(chipKey.Curve, terminalPublicKey)
+	if x == nil {
+		t.Fatal("invalid terminal public key")
+	}
+	sharedX, _ := chipKey.Curve.ScalarMult(x, y, chipKey.D.Bytes())
+
+	macKey, err := deriveKey(sharedX.FillBytes(make([]byte, (chipKey.Curve.Params().BitSize+7)/8)), cipher, macKeyCounter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := []byte{tagStatus, 2, 0x90, 0x00}
+	ssc := append(make([]byte, aes.BlockSize-8), binary.BigEndian.AppendUint64(nil, responseSSC)...)
+	mac, err := cmac(macKey, pad(append(ssc, status...), aes.BlockSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return append(append(status, tagMAC, macSize), append(mac[:macSize], 0x90, 0x00)...)
+}
+
+func TestVerify(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), brainpool.P256r1()} {
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			chipKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key := Key{PublicKey: &chipKey.PublicKey, Cipher: CipherAES128}
+
+			namedCurve, err := certificates.CurveByName(curve.Params().Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			terminalKey, err := GenerateTerminalKey(namedCurve)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := Verify(key, terminalKey, chipResponse(t, chipKey, key.Cipher, terminalKey.PublicKey())); err != nil {
+				t.Fatalf("genuine chip response rejected: %v", err)
+			}
+
+			// the transcript made for a terminal key the service has not generated is useless
+			otherKey, err := GenerateTerminalKey(namedCurve)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = Verify(key, terminalKey, chipResponse(t, chipKey, key.Cipher, otherKey.PublicKey()))
+			if !errors.Is(err, ErrInvalidMAC) {
+				t.Fatalf("expected %v for the response to another terminal key, got %v", ErrInvalidMAC, err)
+			}
+		})
+	}
+
+	t.Run("curve mismatch", func(t *testing.T) {
+		chipKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		terminalKey, err := GenerateTerminalKey(elliptic.P384())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := Verify(Key{PublicKey: &chipKey.PublicKey, Cipher: CipherAES128}, terminalKey, []byte{0x90, 0x00}); err == nil {
+			t.Fatal("terminal key on another curve accepted")
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package chipauth
+
+import (
+	"crypto/aes"
+	"crypto/des"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+var ErrInvalidMAC = errors.New("invalid secure messaging MAC")
+
+// secure messaging data objects, see ICAO 9303 part 11, section 9.8
+const (
+	tagEncryptedData = 0x87
+	tagStatus        = 0x99
+	tagMAC           = 0x8E
+
+	macSize = 8
+	// responseSSC is the send sequence counter of the response to the first command
+	// after chip authentication, the counter is reset to 0 and incremented by each APDU
+	responseSSC = 2
+)
+
+// verifyResponseMAC checks the MAC of the protected response APDU: the data objects
+// followed by the MAC one and the status word.
+func verifyResponseMAC(macKey []byte, c Cipher, response []byte) error {
+	if len(response) < 2 {
+		return errors.New("response APDU is too short")
+	}
+
+	var (
+		macInput []byte
+		mac      []byte
+		status   bool
+	)
+	for rest := response[:len(response)-2]; len(rest) > 0; {
+		if mac != nil {
+			return errors.New("MAC data object is not the last one")
+		}
+
+		tag, value, full, next, err := parseTLV(rest)
+		if err != nil {
+			return errors.Wrap(err, "invalid response APDU")
+		}
+		rest = next
+
+		switch tag {
+		case tagEncryptedData:
+			macInput = append(macInput, full...)
+		case tagStatus:
+			status = true
+			macInput = append(macInput, full...)
+		case tagMAC:
+			if len(value) != macSize {
+				return fmt.Errorf("expected %d-byte MAC, got %d", macSize, len(value))
+			}
+			mac = value
+		default:
+			return fmt.Errorf("unexpected data object %x", tag)
+		}
+	}
+	if !status || mac == nil {
+		return errors.New("response APDU is not protected by secure messaging")
+	}
+
+	var expected []byte
+	switch c {
+	case Cipher3DES:
+		ssc := binary.BigEndian.AppendUint64(nil, responseSSC)
+		expected = retailMAC(macKey, pad(append(ssc, macInput...), des.BlockSize))
+	case CipherAES128, CipherAES192, CipherAES256:
+		ssc := append(make([]byte, aes.BlockSize-8), binary.BigEndian.AppendUint64(nil, responseSSC)...)
+		var err error
+		if expected, err = cmac(macKey, pad(append(ssc, macInput...), aes.BlockSize)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported cipher %s", c)
+	}
+
+	if subtle.ConstantTimeCompare(expected[:macSize], mac) != 1 {
+		return ErrInvalidMAC
+	}
+
+	return nil
+}
+
+// parseTLV parses the BER-TLV data object with a single-byte tag.
+func parseTLV(data []byte) (tag byte, value, full, rest []byte, err error) {
+	if len(data) < 2 {
+		return 0, nil, nil, nil, errors.New("data object is truncated")
+	}
+
+	tag, offset := data[0], 2
+	length := int(data[1])
+	if data[1] > 0x80 {
+		lengthSize := int(data[1] & 0x7F)
+		if lengthSize > 2 || len(data) < 2+lengthSize {
+			return 0, nil, nil, nil, errors.New("invalid data object length")
+		}
+
+		length = 0
+		for _, b := range data[2 : 2+lengthSize] {
+			length = length<<8 | int(b)
+		}
+		offset += lengthSize
+	} else if data[1] == 0x80 {
+		return 0, nil, nil, nil, errors.New("indefinite data object length")
+	}
+
+	if len(data) < offset+length {
+		return 0, nil, nil, nil, errors.New("data object is truncated")
+	}
+
+	return tag, data[offset : offset+length], data[:offset+length], data[offset+length:], nil
+}
+
+// pad applies ISO/IEC 9797-1 padding method 2.
+func pad(data []byte, blockSize int) []byte {
+	padded := append(append([]byte{}, data...), 0x80)
+	for len(padded)%blockSize != 0 {
+		padded = append(padded, 0)
+	}
+
+	return padded
+}
+
+// retailMAC is ISO/IEC 9797-1 MAC algorithm 3 with DES over the padded data.
+func retailMAC(key, data []byte) []byte {
+	// 8-byte keys can not fail
+	ka, _ := des.NewCipher(key[:8])
+	kb, _ := des.NewCipher(key[8:16])
+
+	h := make([]byte, des.BlockSize)
+	for i := 0; i < len(data); i += des.BlockSize {
+		subtle.XORBytes(h, h, data[i:i+des.BlockSize])
+		ka.Encrypt(h, h)
+	}
+
+	kb.Decrypt(h, h)
+	ka.Encrypt(h, h)
+
+	return h
+}
+
+// cmac is the AES-CMAC of NIST SP 800-38B of the data already padded to the block size,
+// so the last block is always a complete one.
+func cmac(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create AES cipher")
+	}
+
+	k1 := make([]byte, aes.BlockSize)
+	block.Encrypt(k1, k1)
+	k1 = doubleBlock(k1)
+
+	h := make([]byte, aes.BlockSize)
+	for i := 0; i < len(data); i += aes.BlockSize {
+		subtle.XORBytes(h, h, data[i:i+aes.BlockSize])
+		if i+aes.BlockSize == len(data) {
+			subtle.XORBytes(h, h, k1)
+		}
+		block.Encrypt(h, h)
+	}
+
+	return h, nil
+}
+
+// doubleBlock multiplies the block by x in GF(2^128).
+func doubleBlock(b []byte) []byte {
+	doubled := make([]byte, len(b))
+	for i := 0; i < len(b)-1; i++ {
+		doubled[i] = b[i]<<1 | b[i+1]>>7
+	}
+	doubled[len(b)-1] = b[len(b)-1] << 1
+
+	if b[0]&0x80 != 0 {
+		doubled[len(b)-1] ^= 0x87
+	}
+
+	return doubled
+}
@@ -0,0 +1,156 @@
+package circuits
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/groth16"
+)
+
+// Public signal names of the passport registration circuits
+const (
+	DG1HashHi        = "dg1_hash_hi"
+	DG1HashLo        = "dg1_hash_lo"
+	IssuingAuthority = "issuing_authority"
+	CurrentYear      = "current_year"
+	CurrentMonth     = "current_month"
+	CurrentDay       = "current_day"
+	ExpiryYear       = "expiry_year"
+	ExpiryMonth      = "expiry_month"
+	ExpiryDay        = "expiry_day"
+	Age              = "age"
+	// Challenge is the server-issued registration nonce, only the circuits committing to it declare it
+	Challenge = "challenge"
+	// DocumentType is the MRZ document code, only the circuits committing to it declare it
+	DocumentType = "document_type"
+)
+
+var (
+	ErrCircuitNotFound = errors.New("circuit not found")
+	ErrNoCircuit       = errors.New("no circuit supports the signature algorithm")
+)
+
+// DeprecatedError is returned for the proofs of the circuits past their deprecation date.
+type DeprecatedError struct {
+	ID    string
+	Since time.Time
+}
+
+func (e *DeprecatedError) Error() string {
+	return fmt.Sprintf("circuit %s is deprecated since %s", e.ID, e.Since.UTC().Format(time.RFC3339))
+}
+
+// Circuit is a registration circuit version.
+type Circuit struct {
+	ID string
+	// Algorithm is the circuit key ID of the signature algorithms the circuit verifies
+	Algorithm string
+	// VerificationKey is the snarkjs JSON verification key
+	VerificationKey []byte
+	// PreparedKey is the VerificationKey parsed into the curve points
+	PreparedKey *groth16.VerifyingKey
+	// Default circuit is used for the algorithm when the client does not specify one
+	Default      bool
+	DeprecatedAt *time.Time
+	// Schema declares the public signals in the order of the proof public inputs
+	Schema []Signal
+}
+
+// Registry holds the circuits by their IDs.
+type Registry struct {
+	byID      map[string]*Circuit
+	defaults  map[string]*Circuit
+	algorithm map[string][]string
+}
+
+func NewRegistry(circuits ...Circuit) (*Registry, error) {
+	registry := Registry{
+		byID:      make(map[string]*Circuit),
+		defaults:  make(map[string]*Circuit),
+		algorithm: make(map[string][]string),
+	}
+
+	for i := range circuits {
+		circuit := circuits[i]
+		if err := validateCircuit(circuit); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid circuit %s", circuit.ID))
+		}
+
+		if _, ok := registry.byID[circuit.ID]; ok {
+			return nil, fmt.Errorf("circuit %s is registered twice", circuit.ID)
+		}
+		registry.byID[circuit.ID] = &circuit
+		registry.algorithm[circuit.Algorithm] = append(registry.algorithm[circuit.Algorithm], circuit.ID)
+
+		if circuit.Default {
+			if other, ok := registry.defaults[circuit.Algorithm]; ok {
+				return nil, fmt.Errorf("circuits %s and %s are both default for %s", other.ID, circuit.ID, circuit.Algorithm)
+			}
+			registry.defaults[circuit.Algorithm] = &circuit
+		}
+	}
+
+	for _, ids := range registry.algorithm {
+		sort.Strings(ids)
+	}
+
+	return &registry, nil
+}
+
+// Select returns the circuit by its ID, or the default circuit of the algorithm when
+// the ID is empty. The circuit must verify the algorithm and must not be deprecated.
+func (r *Registry) Select(algorithm, id string, now time.Time) (*Circuit, error) {
+	var circuit *Circuit
+	if id == "" {
+		var ok bool
+		if circuit, ok = r.defaults[algorithm]; !ok {
+			if len(r.algorithm[algorithm]) == 0 {
+				return nil, ErrNoCircuit
+			}
+			return nil, fmt.Errorf("circuit_id is required for %s, one of %v", algorithm, r.algorithm[algorithm])
+		}
+	} else {
+		var ok bool
+		if circuit, ok = r.byID[id]; !ok {
+			return nil, ErrCircuitNotFound
+		}
+	}
+
+	if circuit.Algorithm != algorithm {
+		return nil, fmt.Errorf("circuit %s verifies %s, not %s", circuit.ID, circuit.Algorithm, algorithm)
+	}
+
+	if circuit.DeprecatedAt != nil && !now.Before(*circuit.DeprecatedAt) {
+		return nil, &DeprecatedError{ID: circuit.ID, Since: *circuit.DeprecatedAt}
+	}
+
+	return circuit, nil
+}
+
+// Len returns the number of the registered circuits.
+func (r *Registry) Len() int {
+	return len(r.byID)
+}
+
+func validateCircuit(circuit Circuit) error {
+	if circuit.ID == "" {
+		return errors.New("empty ID")
+	}
+	if circuit.Algorithm == "" {
+		return errors.New("empty algorithm")
+	}
+	if circuit.PreparedKey == nil {
+		return errors.New("no prepared verification key")
+	}
+	if circuit.PreparedKey.NPublic() != len(circuit.Schema) {
+		return fmt.Errorf("verification key has %d public signals, the schema has %d", circuit.PreparedKey.NPublic(), len(circuit.Schema))
+	}
+	if err := validateSchema(circuit.Schema); err != nil {
+		return errors.Wrap(err, "invalid public signals schema")
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package circuits
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/iden3/go-iden3-crypto/constants"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// SignalType defines how the public signal is decoded and validated.
+type SignalType string
+
+const (
+	// TypeField is any BN254 scalar field element
+	TypeField SignalType = "field"
+	// TypeUint is a non-negative integer that fits int64
+	TypeUint SignalType = "uint"
+	// TypeYear is the last two digits of the year as it is encoded in the MRZ
+	TypeYear  SignalType = "year"
+	TypeMonth SignalType = "month"
+	TypeDay   SignalType = "day"
+	// TypeDocumentCode is the MRZ document code of one or two letters or fillers as
+	// the big-endian ASCII integer, e.g. 20540 for P<
+	TypeDocumentCode SignalType = "document_code"
+)
+
+var signalTypes = map[SignalType]bool{
+	TypeField: true,
+	TypeUint:  true,
+	TypeYear:  true,
+	TypeMonth: true,
+	TypeDay:   true,
+
+	TypeDocumentCode: true,
+}
+
+// Signal is the public signal declaration of the circuit schema.
+type Signal struct {
+	Name string
+	Type SignalType
+}
+
+// DefaultSchema is the public signals schema of the first registration circuits,
+// every circuit schema must declare its signals with the same types.
+var DefaultSchema = []Signal{
+	{Name: DG1HashHi, Type: TypeField},
+	{Name: DG1HashLo, Type: TypeField},
+	{Name: IssuingAuthority, Type: TypeUint},
+	{Name: CurrentYear, Type: TypeYear},
+	{Name: CurrentMonth, Type: TypeMonth},
+	{Name: CurrentDay, Type: TypeDay},
+	{Name: ExpiryYear, Type: TypeYear},
+	{Name: ExpiryMonth, Type: TypeMonth},
+	{Name: ExpiryDay, Type: TypeDay},
+	{Name: Age, Type: TypeUint},
+}
+
+// optionalSchema are the signals the circuits may declare, with the types they must have.
+var optionalSchema = []Signal{
+	{Name: Challenge, Type: TypeField},
+	{Name: DocumentType, Type: TypeDocumentCode},
+}
+
+// Date is the date as it is encoded in the MRZ, the year has two digits.
+type Date struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+// centuryWindow is how far from the reference year the two-digit years are resolved to
+const centuryWindow = 50
+
+// Time returns the midnight UTC of the date. The two-digit year is resolved to the century
+// that puts it within [reference - 50, reference + 50) years, e.g. 30 is 2030 and 80 is 1980
+// when the reference year is 2026.
+func (d Date) Time(reference time.Time) (time.Time, error) {
+	base := reference.Year() - centuryWindow
+	year := base + ((d.Year-base)%100+100)%100
+
+	date := time.Date(year, time.Month(d.Month), d.Day, 0, 0, 0, 0, time.UTC)
+	// time.Date normalizes the overflowing days, e.g. February 30 to March 2
+	if date.Month() != time.Month(d.Month) || date.Day() != d.Day {
+		return time.Time{}, fmt.Errorf("%02d-%02d-%02d is not a valid date", d.Year, d.Month, d.Day)
+	}
+
+	return date, nil
+}
+
+// PubSignals are the decoded and validated public signals of the registration proof.
+type PubSignals struct {
+	DG1HashHi        *big.Int
+	DG1HashLo        *big.Int
+	IssuingAuthority int64
+	CurrentDate      Date
+	ExpiryDate       Date
+	Age              int64
+	// Challenge is nil unless the circuit commits to the registration challenge
+	Challenge *big.Int
+	// DocumentType is the MRZ document code without fillers, e.g. P or ID, it is
+	// empty unless the circuit commits to it
+	DocumentType string
+}
+
+// SignalError points to the public signal that does not match the schema.
+type SignalError struct {
+	Index int
+	Name  string
+	Err   error
+}
+
+func (e *SignalError) Error() string {
+	return fmt.Sprintf("invalid %s public signal %d: %s", e.Name, e.Index, e.Err)
+}
+
+// Decode decodes the public signals according to the circuit schema.
+func (c *Circuit) Decode(pubSignals []string) (*PubSignals, error) {
+	if len(pubSignals) != len(c.Schema) {
+		return nil, fmt.Errorf("circuit %s has %d public signals, got %d", c.ID, len(c.Schema), len(pubSignals))
+	}
+
+	values := make(map[string]*big.Int, len(c.Schema))
+	for i, signal := range c.Schema {
+		value, err := decodeSignal(pubSignals[i], signal.Type)
+		if err != nil {
+			return nil, &SignalError{Index: i, Name: signal.Name, Err: err}
+		}
+
+		values[signal.Name] = value
+	}
+
+	return &PubSignals{
+		DG1HashHi:        values[DG1HashHi],
+		DG1HashLo:        values[DG1HashLo],
+		IssuingAuthority: values[IssuingAuthority].Int64(),
+		CurrentDate: Date{
+			Year:  int(values[CurrentYear].Int64()),
+			Month: int(values[CurrentMonth].Int64()),
+			Day:   int(values[CurrentDay].Int64()),
+		},
+		ExpiryDate: Date{
+			Year:  int(values[ExpiryYear].Int64()),
+			Month: int(values[ExpiryMonth].Int64()),
+			Day:   int(values[ExpiryDay].Int64()),
+		},
+		Age:          values[Age].Int64(),
+		Challenge:    values[Challenge],
+		DocumentType: documentCode(values[DocumentType]),
+	}, nil
+}
+
+func decodeSignal(signal string, signalType SignalType) (*big.Int, error) {
+	value, ok := new(big.Int).SetString(signal, 10)
+	if !ok {
+		return nil, errors.New("not a decimal integer")
+	}
+	if value.Sign() < 0 || value.Cmp(constants.Q) >= 0 {
+		return nil, errors.New("not a field element")
+	}
+
+	switch signalType {
+	case TypeField:
+		return value, nil
+	case TypeUint:
+		if !value.IsInt64() {
+			return nil, errors.New("integer overflow")
+		}
+		return value, nil
+	case TypeYear:
+		return value, checkRange(value, 0, 99)
+	case TypeMonth:
+		return value, checkRange(value, 1, 12)
+	case TypeDay:
+		return value, checkRange(value, 1, 31)
+	case TypeDocumentCode:
+		return value, checkDocumentCode(value)
+	default:
+		return nil, fmt.Errorf("unknown signal type %s", signalType)
+	}
+}
+
+func checkRange(value *big.Int, min, max int64) error {
+	if !value.IsInt64() || value.Int64() < min || value.Int64() > max {
+		return fmt.Errorf("out of range %d..%d", min, max)
+	}
+
+	return nil
+}
+
+func checkDocumentCode(value *big.Int) error {
+	if value.Sign() == 0 || value.BitLen() > 16 {
+		return errors.New("not a one or two character document code")
+	}
+
+	for i, c := range value.Bytes() {
+		// only the second character may be a filler
+		if (c < 'A' || c > 'Z') && (c != '<' || i == 0) {
+			return fmt.Errorf("invalid document code character %q", c)
+		}
+	}
+
+	return nil
+}
+
+// documentCode returns the document code without the fillers, empty for nil.
+func documentCode(value *big.Int) string {
+	if value == nil {
+		return ""
+	}
+
+	return strings.TrimRight(string(value.Bytes()), "<")
+}
+
+// validateSchema checks the schema declares every signal PubSignals is decoded from.
+func validateSchema(schema []Signal) error {
+	declared := make(map[string]SignalType, len(schema))
+	for _, signal := range schema {
+		if _, ok := declared[signal.Name]; ok {
+			return fmt.Errorf("public signal %s is declared twice", signal.Name)
+		}
+		if !signalTypes[signal.Type] {
+			return fmt.Errorf("public signal %s has unknown type %s", signal.Name, signal.Type)
+		}
+
+		declared[signal.Name] = signal.Type
+	}
+
+	for _, required := range DefaultSchema {
+		declaredType, ok := declared[required.Name]
+		if !ok {
+			return fmt.Errorf("public signal %s is missing", required.Name)
+		}
+		if declaredType != required.Type {
+			return fmt.Errorf("public signal %s must be %s, got %s", required.Name, required.Type, declaredType)
+		}
+	}
+
+	for _, optional := range optionalSchema {
+		declaredType, ok := declared[optional.Name]
+		if ok && declaredType != optional.Type {
+			return fmt.Errorf("public signal %s must be %s, got %s", optional.Name, optional.Type, declaredType)
+		}
+	}
+
+	return nil
+}
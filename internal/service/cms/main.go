@@ -0,0 +1,290 @@
+package cms
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/rarimo/certificate-transparency-go/x509"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/algorithms"
+	"github.com/rarimo/passport-identity-provider/internal/service/certificates"
+)
+
+var (
+	OIDSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	OIDMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECPublicKey   = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+)
+
+var (
+	ErrSignerCertificateNotFound = errors.New("signer certificate not found")
+	ErrMessageDigestNotFound     = errors.New("message digest signed attribute not found")
+)
+
+// SignedData is the CMS SignedData content, see RFC 5652, section 5.
+type SignedData struct {
+	ContentType  asn1.ObjectIdentifier
+	Content      []byte
+	Certificates []*x509.Certificate
+	SignerInfos  []SignerInfo
+}
+
+type SignerInfo struct {
+	DigestAlgorithm pkix.AlgorithmIdentifier
+	// SignedAttributes are DER-encoded as the SET OF Attribute the signature is computed over
+	SignedAttributes   []byte
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+
+	issuer       []byte
+	serialNumber *big.Int
+	subjectKeyID []byte
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	// Content is the [0] EXPLICIT wrapper, the content itself is in its Bytes
+	Content asn1.RawValue `asn1:"tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	// EContent is the [0] EXPLICIT wrapper of the OCTET STRING
+	EContent asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// ParseSignedData parses DER-encoded ContentInfo carrying SignedData.
+func ParseSignedData(der []byte) (*SignedData, error) {
+	info := contentInfo{}
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal content info")
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after content info")
+	}
+
+	if !info.ContentType.Equal(OIDSignedData) {
+		return nil, fmt.Errorf("expected signed data content type, got %s", info.ContentType)
+	}
+
+	sd := signedData{}
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &sd); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal signed data")
+	}
+
+	var eContent asn1.RawValue
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent.Bytes, &eContent); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal encapsulated content")
+	}
+	if eContent.Tag != asn1.TagOctetString || eContent.IsCompound {
+		return nil, errors.New("encapsulated content is not a primitive octet string")
+	}
+
+	certificates, err := parseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse certificates")
+	}
+
+	signerInfos := make([]SignerInfo, 0, len(sd.SignerInfos))
+	for i, si := range sd.SignerInfos {
+		signer, err := newSignerInfo(si)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to parse signer info %d", i))
+		}
+
+		signerInfos = append(signerInfos, signer)
+	}
+
+	return &SignedData{
+		ContentType:  sd.EncapContentInfo.EContentType,
+		Content:      eContent.Bytes,
+		Certificates: certificates,
+		SignerInfos:  signerInfos,
+	}, nil
+}
+
+// SignerCertificate finds the embedded certificate identified by the signer info.
+func (s *SignedData) SignerCertificate(signer SignerInfo) (*x509.Certificate, error) {
+	for _, cert := range s.Certificates {
+		if signer.subjectKeyID != nil {
+			if bytes.Equal(cert.SubjectKeyId, signer.subjectKeyID) {
+				return cert, nil
+			}
+			continue
+		}
+
+		if bytes.Equal(cert.RawIssuer, signer.issuer) && cert.SerialNumber.Cmp(signer.serialNumber) == 0 {
+			return cert, nil
+		}
+	}
+
+	return nil, ErrSignerCertificateNotFound
+}
+
+// AlgorithmName returns the signature algorithm OID, or the hash and key algorithm
+// pair, e.g. SHA256withRSA, when the signer declares a bare key algorithm instead.
+func (s SignerInfo) AlgorithmName() (string, error) {
+	var keyAlgorithm string
+	switch {
+	case s.SignatureAlgorithm.Algorithm.Equal(oidRSAEncryption):
+		keyAlgorithm = "RSA"
+	case s.SignatureAlgorithm.Algorithm.Equal(oidECPublicKey):
+		keyAlgorithm = "ECDSA"
+	default:
+		return s.SignatureAlgorithm.Algorithm.String(), nil
+	}
+
+	hash, err := algorithms.HashFromOID(s.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid digest algorithm")
+	}
+
+	return fmt.Sprintf("%swith%s", strings.ReplaceAll(hash.String(), "-", ""), keyAlgorithm), nil
+}
+
+// MessageDigest returns the value of the message digest signed attribute.
+func (s SignerInfo) MessageDigest() ([]byte, error) {
+	return MessageDigest(s.SignedAttributes)
+}
+
+// MessageDigest returns the value of the message digest attribute of the DER-encoded signed
+// attributes. The attributes are a SET, so it is looked up by the type rather than the position,
+// and it must be present exactly once.
+func MessageDigest(signedAttributes []byte) ([]byte, error) {
+	attributes := make([]attribute, 0)
+	if _, err := asn1.UnmarshalWithParams(signedAttributes, &attributes, "set"); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal signed attributes")
+	}
+
+	var digest []byte
+	for _, attr := range attributes {
+		if !attr.Type.Equal(OIDMessageDigest) {
+			continue
+		}
+		if digest != nil {
+			return nil, errors.New("message digest signed attribute is duplicated")
+		}
+
+		if len(attr.Values) != 1 {
+			return nil, fmt.Errorf("expected exactly one message digest value, got %d", len(attr.Values))
+		}
+
+		if _, err := asn1.Unmarshal(attr.Values[0].FullBytes, &digest); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal message digest")
+		}
+		if digest == nil {
+			digest = []byte{}
+		}
+	}
+
+	if digest == nil {
+		return nil, ErrMessageDigestNotFound
+	}
+
+	return digest, nil
+}
+
+// AlgorithmParameters returns DER-encoded signature algorithm parameters, if any.
+func (s SignerInfo) AlgorithmParameters() []byte {
+	return s.SignatureAlgorithm.Parameters.FullBytes
+}
+
+func newSignerInfo(si signerInfo) (SignerInfo, error) {
+	if len(si.SignedAttrs.FullBytes) == 0 {
+		return SignerInfo{}, errors.New("signed attributes are missing")
+	}
+
+	// signature covers the attributes encoded as SET OF rather than the implicit [0] tag
+	signedAttributes := make([]byte, len(si.SignedAttrs.FullBytes))
+	copy(signedAttributes, si.SignedAttrs.FullBytes)
+	signedAttributes[0] = asn1.TagSet | 0x20
+
+	signer := SignerInfo{
+		DigestAlgorithm:    si.DigestAlgorithm,
+		SignedAttributes:   signedAttributes,
+		SignatureAlgorithm: si.SignatureAlgorithm,
+		Signature:          si.Signature,
+	}
+
+	switch {
+	case si.SID.Class == asn1.ClassContextSpecific && si.SID.Tag == 0:
+		signer.subjectKeyID = si.SID.Bytes
+	case si.SID.Class == asn1.ClassUniversal && si.SID.Tag == asn1.TagSequence:
+		sid := issuerAndSerialNumber{}
+		if _, err := asn1.Unmarshal(si.SID.FullBytes, &sid); err != nil {
+			return SignerInfo{}, errors.Wrap(err, "failed to unmarshal issuer and serial number")
+		}
+
+		signer.issuer = sid.Issuer.FullBytes
+		signer.serialNumber = sid.SerialNumber
+	default:
+		return SignerInfo{}, errors.New("unsupported signer identifier")
+	}
+
+	return signer, nil
+}
+
+func parseCertificates(raw []byte) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0)
+
+	for len(raw) > 0 {
+		var certASN1 asn1.RawValue
+
+		rest, err := asn1.Unmarshal(raw, &certASN1)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal certificate")
+		}
+		raw = rest
+
+		// other certificate formats are not used in the travel documents PKI
+		if certASN1.Tag != asn1.TagSequence {
+			continue
+		}
+
+		cert, err := certificates.Parse(certASN1.FullBytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse certificate")
+		}
+
+		certs = append(certs, cert)
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,76 @@
+package cms
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"testing"
+)
+
+var oidContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+
+// signedAttributes encodes the attributes as a SET in the given order, asn1.Marshal would sort them.
+func signedAttributes(t *testing.T, attributes ...attribute) []byte {
+	t.Helper()
+
+	var content []byte
+	for _, attr := range attributes {
+		raw, err := asn1.Marshal(attr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content = append(content, raw...)
+	}
+
+	raw, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: content})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return raw
+}
+
+func newAttribute(t *testing.T, oid asn1.ObjectIdentifier, value interface{}) attribute {
+	t.Helper()
+
+	raw, err := asn1.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return attribute{Type: oid, Values: []asn1.RawValue{{FullBytes: raw}}}
+}
+
+func TestMessageDigest(t *testing.T) {
+	digest := bytes.Repeat([]byte{0xab}, 32)
+	contentType := newAttribute(t, oidContentType, asn1.ObjectIdentifier{2, 23, 136, 1, 1, 1})
+	messageDigest := newAttribute(t, OIDMessageDigest, digest)
+
+	for name, attributes := range map[string][]attribute{
+		"digest last":  {contentType, messageDigest},
+		"digest first": {messageDigest, contentType},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := MessageDigest(signedAttributes(t, attributes...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, digest) {
+				t.Fatalf("got digest %x, want %x", got, digest)
+			}
+		})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := MessageDigest(signedAttributes(t, contentType))
+		if !errors.Is(err, ErrMessageDigestNotFound) {
+			t.Fatalf("got %v, want %v", err, ErrMessageDigestNotFound)
+		}
+	})
+
+	t.Run("duplicated", func(t *testing.T) {
+		if _, err := MessageDigest(signedAttributes(t, messageDigest, contentType, messageDigest)); err == nil {
+			t.Fatal("duplicated message digest is accepted")
+		}
+	})
+}
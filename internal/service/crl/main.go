@@ -0,0 +1,327 @@
+package crl
+
+import (
+	"context"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rarimo/certificate-transparency-go/x509"
+	"github.com/rarimo/certificate-transparency-go/x509/pkix"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+)
+
+var reasons = map[x509.RevocationReasonCode]string{
+	x509.Unspecified:          "unspecified",
+	x509.KeyCompromise:        "keyCompromise",
+	x509.CACompromise:         "cACompromise",
+	x509.AffiliationChanged:   "affiliationChanged",
+	x509.Superseded:           "superseded",
+	x509.CessationOfOperation: "cessationOfOperation",
+	x509.CertificateHold:      "certificateHold",
+	x509.RemoveFromCRL:        "removeFromCRL",
+	x509.PrivilegeWithdrawn:   "privilegeWithdrawn",
+	x509.AACompromise:         "aACompromise",
+}
+
+// RevokedError is returned for the certificates listed in the CRL of their issuer.
+type RevokedError struct {
+	SerialNumber *big.Int
+	RevokedAt    time.Time
+	Reason       string
+}
+
+func (e *RevokedError) Error() string {
+	return fmt.Sprintf("certificate %s is revoked since %s, reason: %s",
+		e.SerialNumber.Text(16), e.RevokedAt.UTC().Format(time.RFC3339), e.Reason)
+}
+
+// Errors of the fail-closed checks, the certificates of the issuers without a valid CRL
+// are rejected with them.
+var (
+	ErrNoCRL       = errors.New("no valid CRL of the certificate issuer is loaded")
+	ErrOutdatedCRL = errors.New("CRL of the certificate issuer is outdated")
+)
+
+// issuerCRL is the revoked certificates by the serial number from the verified CRLs of the
+// issuer and when the latest of them is due to be updated.
+type issuerCRL struct {
+	revoked    map[string]*RevokedError
+	nextUpdate time.Time
+}
+
+// revocations are the CRLs by the DER-encoded issuer
+type revocations map[string]*issuerCRL
+
+// IssuerStore looks up the CSCAs that might have signed the CRL.
+type IssuerStore interface {
+	Issuers(subject, keyID []byte) []*x509.Certificate
+}
+
+// Checker keeps the revoked certificates from the verified CRLs and refreshes them periodically.
+// Unless it fails open, the certificates of the issuers without a valid CRL or with the CRL
+// past its next update are rejected, so a missing, forged or stale CRL does not let the
+// revoked certificates through.
+type Checker struct {
+	log    *logan.Entry
+	cfg    *config.CRLConfig
+	store  IssuerStore
+	client *http.Client
+
+	mu      sync.RWMutex
+	revoked revocations
+}
+
+func NewChecker(log *logan.Entry, cfg *config.CRLConfig, store IssuerStore) *Checker {
+	return &Checker{
+		log:     log,
+		cfg:     cfg,
+		store:   store,
+		client:  &http.Client{Timeout: cfg.RequestTimeout},
+		revoked: make(revocations),
+	}
+}
+
+// Check returns RevokedError if the certificate is revoked by its issuer. Unless the checker
+// fails open, ErrNoCRL is returned if the issuer has no valid CRL and ErrOutdatedCRL if its
+// CRL is past the next update.
+func (c *Checker) Check(cert *x509.Certificate) error {
+	if !c.cfg.Enabled() {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	crl, ok := c.revoked[string(cert.RawIssuer)]
+	if !ok {
+		if c.cfg.FailOpen {
+			return nil
+		}
+		return ErrNoCRL
+	}
+
+	if revoked, ok := crl.revoked[cert.SerialNumber.String()]; ok {
+		return revoked
+	}
+
+	if !c.cfg.FailOpen && !crl.nextUpdate.IsZero() && crl.nextUpdate.Before(time.Now()) {
+		return fmt.Errorf("%w, the next update was due at %s", ErrOutdatedCRL, crl.nextUpdate.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// Run refreshes the CRLs every refresh period until the context is done.
+func (c *Checker) Run(ctx context.Context) {
+	if !c.cfg.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(c.cfg.RefreshPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil {
+				c.log.WithError(err).Error("failed to refresh CRLs, keeping the previous ones")
+			}
+		}
+	}
+}
+
+// Refresh loads the CRLs from all the sources and replaces the revoked certificates with
+// the ones listed in the CRLs signed by the trusted CSCAs. If any source is unavailable
+// the previous revoked certificates are kept.
+func (c *Checker) Refresh(ctx context.Context) error {
+	if !c.cfg.Enabled() {
+		return nil
+	}
+
+	raws, err := c.load(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to load CRLs")
+	}
+
+	revoked := make(revocations)
+	for source, raw := range raws {
+		crl, issuer, err := c.parse(raw)
+		if err != nil {
+			c.log.WithError(err).WithField("source", source).Warn("skipped invalid CRL")
+			continue
+		}
+
+		if crl.TBSCertList.NextUpdate.Before(time.Now()) {
+			c.log.WithFields(logan.F{
+				"source":      source,
+				"next_update": crl.TBSCertList.NextUpdate,
+			}).Warn("CRL is outdated")
+		}
+
+		issuerRevoked := revoked[string(issuer)]
+		if issuerRevoked == nil {
+			issuerRevoked = &issuerCRL{revoked: make(map[string]*RevokedError)}
+			revoked[string(issuer)] = issuerRevoked
+		}
+		if crl.TBSCertList.NextUpdate.After(issuerRevoked.nextUpdate) {
+			issuerRevoked.nextUpdate = crl.TBSCertList.NextUpdate
+		}
+		for _, cert := range crl.TBSCertList.RevokedCertificates {
+			issuerRevoked.revoked[cert.SerialNumber.String()] = &RevokedError{
+				SerialNumber: cert.SerialNumber,
+				RevokedAt:    cert.RevocationTime,
+				Reason:       reason(cert.RevocationReason),
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.revoked = revoked
+	c.mu.Unlock()
+
+	c.log.WithFields(logan.F{
+		"sources": len(raws),
+		"issuers": len(revoked),
+	}).Info("CRLs refreshed")
+	return nil
+}
+
+// parse parses the CRL and verifies it is signed by one of the trusted CSCAs,
+// the DER-encoded CRL issuer is returned along.
+func (c *Checker) parse(raw []byte) (*x509.CertificateList, []byte, error) {
+	crl, err := x509.ParseCertificateList(raw)
+	if err != nil && (crl == nil || x509.IsFatal(err)) {
+		return nil, nil, errors.Wrap(err, "failed to parse CRL")
+	}
+
+	issuer, err := rawIssuer(crl.TBSCertList.Raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pkixCRL := pkix.CertificateList{
+		TBSCertList:        pkix.TBSCertificateList{Raw: crl.TBSCertList.Raw},
+		SignatureAlgorithm: crl.SignatureAlgorithm,
+		SignatureValue:     crl.SignatureValue,
+	}
+
+	for _, csca := range c.store.Issuers(issuer, crl.TBSCertList.AuthorityKeyID) {
+		if err := csca.CheckCRLSignature(&pkixCRL); err == nil {
+			return crl, issuer, nil
+		}
+	}
+
+	return nil, nil, errors.New("CRL is not signed by any trusted CSCA")
+}
+
+func (c *Checker) load(ctx context.Context) (map[string][]byte, error) {
+	raws := make(map[string][]byte)
+
+	if c.cfg.Dir != "" {
+		entries, err := os.ReadDir(c.cfg.Dir)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read CRL directory")
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+
+			path := filepath.Join(c.cfg.Dir, entry.Name())
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("failed to read %s", path))
+			}
+
+			raws[path] = raw
+		}
+	}
+
+	for _, url := range c.cfg.URLs {
+		raw, err := c.download(ctx, url)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to download %s", url))
+		}
+
+		raws[url] = raw
+	}
+
+	return raws, nil
+}
+
+func (c *Checker) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+
+	return raw, nil
+}
+
+// rawIssuer extracts the DER-encoded issuer name from TBSCertList, as re-encoding
+// the parsed name may change the string types and not match the certificate issuer.
+func rawIssuer(tbsCertList []byte) ([]byte, error) {
+	var tbs asn1.RawValue
+	if _, err := asn1.Unmarshal(tbsCertList, &tbs); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal TBS cert list")
+	}
+
+	// the optional version is followed by the signature algorithm and the issuer
+	fields := make([]asn1.RawValue, 0, 3)
+	for rest := tbs.Bytes; len(rest) > 0 && len(fields) < 3; {
+		var field asn1.RawValue
+
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &field); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal TBS cert list field")
+		}
+
+		fields = append(fields, field)
+	}
+
+	issuerIndex := 1
+	if len(fields) > 0 && fields[0].Tag == asn1.TagInteger {
+		issuerIndex++
+	}
+	if len(fields) <= issuerIndex {
+		return nil, errors.New("CRL issuer is missing")
+	}
+
+	return fields[issuerIndex].FullBytes, nil
+}
+
+func reason(code x509.RevocationReasonCode) string {
+	if name, ok := reasons[code]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("unknown (%d)", code)
+}
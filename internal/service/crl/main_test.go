@@ -0,0 +1,157 @@
+package crl
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	stdx509 "crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rarimo/certificate-transparency-go/x509"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+)
+
+// staticStore offers all the CSCAs for every CRL.
+type staticStore []*x509.Certificate
+
+func (s staticStore) Issuers([]byte, []byte) []*x509.Certificate {
+	return s
+}
+
+// newCertificate issues the certificate by the parent, a self-signed CSCA if it is nil.
+func newCertificate(t *testing.T, name string, serial int64, parent *stdx509.Certificate, parentKey *ecdsa.PrivateKey) (*stdx509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &stdx509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name, Country: []string{"UA"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              stdx509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.KeyUsage |= stdx509.KeyUsageCertSign | stdx509.KeyUsageCRLSign
+		parent, parentKey = template, key
+	}
+
+	raw, err := stdx509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := stdx509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key
+}
+
+func parse(t *testing.T, cert *stdx509.Certificate) *x509.Certificate {
+	t.Helper()
+
+	parsed, err := x509.ParseCertificate(cert.Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return parsed
+}
+
+// writeCRL writes the CRL of the CSCA revoking the serial numbers to the directory.
+func writeCRL(t *testing.T, dir string, csca *stdx509.Certificate, key *ecdsa.PrivateKey, nextUpdate time.Time, serials ...int64) {
+	t.Helper()
+
+	template := &stdx509.RevocationList{
+		Number:     big.NewInt(time.Now().UnixNano()),
+		ThisUpdate: nextUpdate.Add(-2 * time.Hour),
+		NextUpdate: nextUpdate,
+	}
+	for _, serial := range serials {
+		template.RevokedCertificates = append(template.RevokedCertificates, pkix.RevokedCertificate{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now().Add(-time.Minute),
+		})
+	}
+
+	raw, err := stdx509.CreateRevocationList(rand.Reader, template, csca, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, csca.Subject.CommonName+".crl"), raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	csca, cscaKey := newCertificate(t, "csca", 1, nil, nil)
+	uncovered, uncoveredKey := newCertificate(t, "uncovered", 2, nil, nil)
+	revoked, _ := newCertificate(t, "revoked", 7, csca, cscaKey)
+	valid, _ := newCertificate(t, "valid", 8, csca, cscaKey)
+	uncoveredSigner, _ := newCertificate(t, "uncovered signer", 9, uncovered, uncoveredKey)
+
+	// the CRL of the uncovered CSCA is forged
+	forger, forgerKey := newCertificate(t, "uncovered", 3, nil, nil)
+
+	dir := t.TempDir()
+	writeCRL(t, dir, csca, cscaKey, time.Now().Add(time.Hour), 7)
+	writeCRL(t, dir, forger, forgerKey, time.Now().Add(time.Hour), 9)
+
+	store := staticStore{parse(t, csca), parse(t, uncovered)}
+	newChecker := func(failOpen bool) *Checker {
+		checker := NewChecker(logan.New(), &config.CRLConfig{Dir: dir, FailOpen: failOpen}, store)
+		if err := checker.Refresh(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return checker
+	}
+
+	var revokedErr *RevokedError
+	for _, failOpen := range []bool{false, true} {
+		checker := newChecker(failOpen)
+		if err := checker.Check(parse(t, revoked)); !errors.As(err, &revokedErr) || revokedErr.SerialNumber.Int64() != 7 {
+			t.Errorf("fail open %t: expected the revoked certificate rejected, got %v", failOpen, err)
+		}
+		if err := checker.Check(parse(t, valid)); err != nil {
+			t.Errorf("fail open %t: expected the valid certificate accepted, got %v", failOpen, err)
+		}
+	}
+
+	if err := newChecker(false).Check(parse(t, uncoveredSigner)); !errors.Is(err, ErrNoCRL) {
+		t.Errorf("expected %v for the issuer with the forged CRL, got %v", ErrNoCRL, err)
+	}
+	if err := newChecker(true).Check(parse(t, uncoveredSigner)); err != nil {
+		t.Errorf("expected the issuer without a CRL accepted in the fail open mode, got %v", err)
+	}
+
+	// no CRL is loaded before the first refresh
+	empty := NewChecker(logan.New(), &config.CRLConfig{Dir: dir}, store)
+	if err := empty.Check(parse(t, valid)); !errors.Is(err, ErrNoCRL) {
+		t.Errorf("expected %v before the CRLs are loaded, got %v", ErrNoCRL, err)
+	}
+
+	writeCRL(t, dir, csca, cscaKey, time.Now().Add(-time.Minute), 7)
+	if err := newChecker(false).Check(parse(t, valid)); !errors.Is(err, ErrOutdatedCRL) {
+		t.Errorf("expected %v for the CRL past the next update, got %v", ErrOutdatedCRL, err)
+	}
+	if err := newChecker(true).Check(parse(t, valid)); err != nil {
+		t.Errorf("expected the outdated CRL accepted in the fail open mode, got %v", err)
+	}
+}
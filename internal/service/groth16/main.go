@@ -0,0 +1,266 @@
+package groth16
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/iden3/go-rapidsnark/types"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+var ErrInvalidProof = errors.New("invalid proof")
+
+// VerifyingKey is the snarkjs Groth16 verification key parsed into the BN254 curve points.
+type VerifyingKey struct {
+	Alpha bn254.G1Affine
+	Beta  bn254.G2Affine
+	Gamma bn254.G2Affine
+	Delta bn254.G2Affine
+	IC    []bn254.G1Affine
+
+	// alphaBeta is e(alpha, beta), it is the same for every proof
+	alphaBeta bn254.GT
+}
+
+// verifyingKeyJSON is the snarkjs verification key format.
+type verifyingKeyJSON struct {
+	Protocol string     `json:"protocol"`
+	Curve    string     `json:"curve"`
+	NPublic  int        `json:"nPublic"`
+	Alpha    []string   `json:"vk_alpha_1"`
+	Beta     [][]string `json:"vk_beta_2"`
+	Gamma    [][]string `json:"vk_gamma_2"`
+	Delta    [][]string `json:"vk_delta_2"`
+	IC       [][]string `json:"IC"`
+}
+
+// ParseVerifyingKey parses and validates the snarkjs JSON verification key.
+func ParseVerifyingKey(raw []byte) (*VerifyingKey, error) {
+	var vkJSON verifyingKeyJSON
+	if err := json.Unmarshal(raw, &vkJSON); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal verification key")
+	}
+
+	if vkJSON.Protocol != "groth16" || vkJSON.Curve != "bn128" {
+		return nil, fmt.Errorf("expected groth16 bn128 key, got %s %s", vkJSON.Protocol, vkJSON.Curve)
+	}
+	if len(vkJSON.IC) != vkJSON.NPublic+1 {
+		return nil, fmt.Errorf("expected %d IC points for %d public signals, got %d", vkJSON.NPublic+1, vkJSON.NPublic, len(vkJSON.IC))
+	}
+
+	var (
+		vk  VerifyingKey
+		err error
+	)
+
+	if vk.Alpha, err = parseG1(vkJSON.Alpha); err != nil {
+		return nil, errors.Wrap(err, "invalid vk_alpha_1")
+	}
+	if vk.Beta, err = parseG2(vkJSON.Beta); err != nil {
+		return nil, errors.Wrap(err, "invalid vk_beta_2")
+	}
+	if vk.Gamma, err = parseG2(vkJSON.Gamma); err != nil {
+		return nil, errors.Wrap(err, "invalid vk_gamma_2")
+	}
+	if vk.Delta, err = parseG2(vkJSON.Delta); err != nil {
+		return nil, errors.Wrap(err, "invalid vk_delta_2")
+	}
+
+	vk.IC = make([]bn254.G1Affine, len(vkJSON.IC))
+	for i, point := range vkJSON.IC {
+		if vk.IC[i], err = parseG1(point); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid IC %d", i))
+		}
+	}
+
+	if vk.alphaBeta, err = bn254.Pair([]bn254.G1Affine{vk.Alpha}, []bn254.G2Affine{vk.Beta}); err != nil {
+		return nil, errors.Wrap(err, "failed to compute e(alpha, beta)")
+	}
+
+	return &vk, nil
+}
+
+// NPublic returns the number of the public signals the key verifies.
+func (vk *VerifyingKey) NPublic() int {
+	return len(vk.IC) - 1
+}
+
+// Verify checks e(A, B) = e(alpha, beta) * e(vk_x, gamma) * e(C, delta), where
+// vk_x = IC[0] + sum(pub_signals[i] * IC[i+1]).
+func (vk *VerifyingKey) Verify(zkProof types.ZKProof) error {
+	if len(zkProof.PubSignals) != vk.NPublic() {
+		return fmt.Errorf("expected %d public signals, got %d", vk.NPublic(), len(zkProof.PubSignals))
+	}
+
+	a, b, c, err := parseProof(zkProof.Proof)
+	if err != nil {
+		return err
+	}
+
+	scalars := make([]fr.Element, len(zkProof.PubSignals))
+	for i, signal := range zkProof.PubSignals {
+		value, err := parseInt(signal, fr.Modulus())
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("invalid public signal %d", i))
+		}
+		scalars[i].SetBigInt(value)
+	}
+
+	var vkX bn254.G1Affine
+	if _, err := vkX.MultiExp(vk.IC[1:], scalars, ecc.MultiExpConfig{}); err != nil {
+		return errors.Wrap(err, "failed to compute vk_x")
+	}
+	vkX.Add(&vkX, &vk.IC[0])
+
+	// e(A, B) * e(-vk_x, gamma) * e(-C, delta) must be e(alpha, beta)
+	vkX.Neg(&vkX)
+	c.Neg(&c)
+	ml, err := bn254.MillerLoop([]bn254.G1Affine{a, vkX, c}, []bn254.G2Affine{b, vk.Gamma, vk.Delta})
+	if err != nil {
+		return errors.Wrap(err, "failed to compute pairing")
+	}
+
+	result := bn254.FinalExponentiation(&ml)
+	if !result.Equal(&vk.alphaBeta) {
+		return ErrInvalidProof
+	}
+
+	return nil
+}
+
+// StatementHash returns the hash of the statement the proof proves: the SOD signed attributes
+// and the public signals in their canonical encoding. Groth16 proofs are malleable, anyone may
+// rerandomize an accepted proof, so replays are detected by the statement rather than the proof.
+func StatementHash(signedAttributes []byte, pubSignals []string) ([]byte, error) {
+	attributesHash := sha256.Sum256(signedAttributes)
+
+	hash := sha256.New()
+	hash.Write(attributesHash[:])
+	for i, signal := range pubSignals {
+		value, err := parseInt(signal, fr.Modulus())
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid public signal %d", i))
+		}
+
+		var element fr.Element
+		elementBytes := element.SetBigInt(value).Bytes()
+		hash.Write(elementBytes[:])
+	}
+
+	return hash.Sum(nil), nil
+}
+
+func parseProof(proof *types.ProofData) (a bn254.G1Affine, b bn254.G2Affine, c bn254.G1Affine, err error) {
+	if proof == nil {
+		return a, b, c, errors.New("proof is missing")
+	}
+
+	if a, err = parseG1(proof.A); err != nil {
+		return a, b, c, errors.Wrap(err, "invalid pi_a")
+	}
+	if b, err = parseG2(proof.B); err != nil {
+		return a, b, c, errors.Wrap(err, "invalid pi_b")
+	}
+	if c, err = parseG1(proof.C); err != nil {
+		return a, b, c, errors.Wrap(err, "invalid pi_c")
+	}
+
+	return a, b, c, nil
+}
+
+// parseG1 parses the snarkjs projective [x, y, z] point, z is 1 or 0 for the infinity.
+func parseG1(point []string) (bn254.G1Affine, error) {
+	var p bn254.G1Affine
+	if len(point) != 3 {
+		return p, fmt.Errorf("expected 3 coordinates, got %d", len(point))
+	}
+
+	switch point[2] {
+	case "0":
+		return p, nil
+	case "1":
+	default:
+		return p, errors.New("point is not affine")
+	}
+
+	if err := parseFp(&p.X, point[0]); err != nil {
+		return p, errors.Wrap(err, "invalid x")
+	}
+	if err := parseFp(&p.Y, point[1]); err != nil {
+		return p, errors.Wrap(err, "invalid y")
+	}
+
+	if !p.IsOnCurve() || !p.IsInSubGroup() {
+		return p, errors.New("point is not in G1")
+	}
+
+	return p, nil
+}
+
+// parseG2 parses the snarkjs projective [[x0, x1], [y0, y1], [z0, z1]] point.
+func parseG2(point [][]string) (bn254.G2Affine, error) {
+	var p bn254.G2Affine
+	if len(point) != 3 {
+		return p, fmt.Errorf("expected 3 coordinates, got %d", len(point))
+	}
+	for _, coordinate := range point {
+		if len(coordinate) != 2 {
+			return p, errors.New("expected Fp2 coordinates")
+		}
+	}
+
+	switch {
+	case point[2][0] == "0" && point[2][1] == "0":
+		return p, nil
+	case point[2][0] == "1" && point[2][1] == "0":
+	default:
+		return p, errors.New("point is not affine")
+	}
+
+	for _, coordinate := range []struct {
+		element *fp.Element
+		value   string
+	}{
+		{&p.X.A0, point[0][0]}, {&p.X.A1, point[0][1]},
+		{&p.Y.A0, point[1][0]}, {&p.Y.A1, point[1][1]},
+	} {
+		if err := parseFp(coordinate.element, coordinate.value); err != nil {
+			return p, err
+		}
+	}
+
+	if !p.IsOnCurve() || !p.IsInSubGroup() {
+		return p, errors.New("point is not in G2")
+	}
+
+	return p, nil
+}
+
+func parseFp(element *fp.Element, value string) error {
+	n, err := parseInt(value, fp.Modulus())
+	if err != nil {
+		return err
+	}
+
+	element.SetBigInt(n)
+	return nil
+}
+
+// parseInt parses the decimal integer, it must be less than the field modulus.
+func parseInt(value string, modulus *big.Int) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a decimal integer", value)
+	}
+	if n.Sign() < 0 || n.Cmp(modulus) >= 0 {
+		return nil, errors.New("integer is out of the field")
+	}
+
+	return n, nil
+}
@@ -0,0 +1,293 @@
+package groth16
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/iden3/go-rapidsnark/types"
+	"github.com/iden3/go-rapidsnark/verifier"
+)
+
+// bundledKeys are the verification keys shipped with the service.
+var bundledKeys = []string{"sha1_verification_key.json", "sha256_verification_key.json"}
+
+// backends are the verifiers the service may be configured with, they must agree.
+var backends = map[string]func(raw []byte, vk *VerifyingKey, proof types.ZKProof) error{
+	"native": func(_ []byte, vk *VerifyingKey, proof types.ZKProof) error {
+		return vk.Verify(proof)
+	},
+	"rapidsnark": func(raw []byte, _ *VerifyingKey, proof types.ZKProof) error {
+		return verifier.VerifyGroth16(proof, raw)
+	},
+}
+
+func readBundledKey(tb testing.TB, name string) ([]byte, *VerifyingKey) {
+	tb.Helper()
+
+	raw, err := os.ReadFile(filepath.Join("..", "..", "..", name))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	vk, err := ParseVerifyingKey(raw)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return raw, vk
+}
+
+// newTrapdoorKey builds the verification key of the known trapdoor and a valid proof for it.
+// The proof is computed from the trapdoor, as no proving key is shipped for the bundled keys:
+// A = r*G1, B = s*G2, C = (r*s - alpha*beta - vk_x*gamma) / delta * G1.
+func newTrapdoorKey(t *testing.T, nPublic int) ([]byte, types.ZKProof) {
+	t.Helper()
+
+	random := func() fr.Element {
+		var e fr.Element
+		if _, err := e.SetRandom(); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	alpha, beta, gamma, delta := random(), random(), random(), random()
+	ic := make([]fr.Element, nPublic+1)
+	for i := range ic {
+		ic[i] = random()
+	}
+
+	pubSignals := make([]string, nPublic)
+	vkX := ic[0]
+	for i := range pubSignals {
+		signal := random()
+		pubSignals[i] = signal.String()
+
+		var term fr.Element
+		term.Mul(&signal, &ic[i+1])
+		vkX.Add(&vkX, &term)
+	}
+
+	r, s := random(), random()
+	var c, term fr.Element
+	c.Mul(&r, &s)
+	term.Mul(&alpha, &beta)
+	c.Sub(&c, &term)
+	term.Mul(&vkX, &gamma)
+	c.Sub(&c, &term)
+	term.Inverse(&delta)
+	c.Mul(&c, &term)
+
+	icPoints := make([][]string, len(ic))
+	for i := range ic {
+		icPoints[i] = g1JSON(ic[i])
+	}
+
+	raw, err := json.Marshal(verifyingKeyJSON{
+		Protocol: "groth16",
+		Curve:    "bn128",
+		NPublic:  nPublic,
+		Alpha:    g1JSON(alpha),
+		Beta:     g2JSON(beta),
+		Gamma:    g2JSON(gamma),
+		Delta:    g2JSON(delta),
+		IC:       icPoints,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return raw, types.ZKProof{
+		Proof: &types.ProofData{
+			A:        g1JSON(r),
+			B:        g2JSON(s),
+			C:        g1JSON(c),
+			Protocol: "groth16",
+		},
+		PubSignals: pubSignals,
+	}
+}
+
+func g1JSON(scalar fr.Element) []string {
+	_, _, g1, _ := bn254.Generators()
+
+	var point bn254.G1Affine
+	point.ScalarMultiplication(&g1, scalar.BigInt(new(big.Int)))
+
+	return []string{point.X.String(), point.Y.String(), "1"}
+}
+
+func g2JSON(scalar fr.Element) [][]string {
+	_, _, _, g2 := bn254.Generators()
+
+	var point bn254.G2Affine
+	point.ScalarMultiplication(&g2, scalar.BigInt(new(big.Int)))
+
+	return [][]string{
+		{point.X.A0.String(), point.X.A1.String()},
+		{point.Y.A0.String(), point.Y.A1.String()},
+		{"1", "0"},
+	}
+}
+
+// wellFormedProof returns the proof of the doubled curve generators, it does not verify but
+// costs the same as a valid one, as the pairing is computed in full either way. The generators
+// themselves are not used as rapidsnark misreads the G1 one with x = 1.
+func wellFormedProof(nPublic int) types.ZKProof {
+	var two fr.Element
+	two.SetUint64(2)
+
+	pubSignals := make([]string, nPublic)
+	for i := range pubSignals {
+		pubSignals[i] = "1"
+	}
+
+	return types.ZKProof{
+		Proof: &types.ProofData{
+			A:        g1JSON(two),
+			B:        g2JSON(two),
+			C:        g1JSON(two),
+			Protocol: "groth16",
+		},
+		PubSignals: pubSignals,
+	}
+}
+
+// tampered returns the copies of the proof with one of the signals or points changed.
+func tampered(proof types.ZKProof) map[string]types.ZKProof {
+	result := make(map[string]types.ZKProof)
+
+	signals := make([]string, len(proof.PubSignals))
+	copy(signals, proof.PubSignals)
+	value, _ := new(big.Int).SetString(signals[0], 10)
+	signals[0] = value.Add(value, big.NewInt(1)).String()
+	result["pub signal"] = types.ZKProof{Proof: proof.Proof, PubSignals: signals}
+
+	swapped := *proof.Proof
+	swapped.A, swapped.C = proof.Proof.C, proof.Proof.A
+	result["points"] = types.ZKProof{Proof: &swapped, PubSignals: proof.PubSignals}
+
+	return result
+}
+
+func TestBackendsAgree(t *testing.T) {
+	raw, proof := newTrapdoorKey(t, 10)
+	vk, err := ParseVerifyingKey(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, verify := range backends {
+		if err := verify(raw, vk, proof); err != nil {
+			t.Errorf("%s: valid proof rejected: %v", name, err)
+		}
+
+		for tamper, proof := range tampered(proof) {
+			if err := verify(raw, vk, proof); err == nil {
+				t.Errorf("%s: proof with tampered %s accepted", name, tamper)
+			}
+		}
+	}
+}
+
+func TestBackendsAgreeOnBundledKeys(t *testing.T) {
+	for _, key := range bundledKeys {
+		raw, vk := readBundledKey(t, key)
+		proof := wellFormedProof(vk.NPublic())
+
+		for name, verify := range backends {
+			if err := verify(raw, vk, proof); err == nil {
+				t.Errorf("%s: %s accepted the proof not made for it", name, key)
+			}
+
+			for tamper, proof := range tampered(proof) {
+				if err := verify(raw, vk, proof); err == nil {
+					t.Errorf("%s: %s accepted the proof with tampered %s", name, key, tamper)
+				}
+			}
+		}
+	}
+}
+
+func benchmarkBackend(b *testing.B, backend string) {
+	verify := backends[backend]
+
+	for _, key := range bundledKeys {
+		raw, vk := readBundledKey(b, key)
+		proof := wellFormedProof(vk.NPublic())
+
+		b.Run(key, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = verify(raw, vk, proof)
+			}
+		})
+	}
+}
+
+func BenchmarkNative(b *testing.B) {
+	benchmarkBackend(b, "native")
+}
+
+func BenchmarkRapidsnark(b *testing.B) {
+	benchmarkBackend(b, "rapidsnark")
+}
+
+func TestStatementHash(t *testing.T) {
+	raw, proof := newTrapdoorKey(t, 2)
+	vk, err := ParseVerifyingKey(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// e(k*A, B/k) = e(A, B), the rerandomized proof is another valid proof of the statement
+	a, b, c, err := parseProof(proof.Proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var k, kInverse fr.Element
+	k.SetUint64(7)
+	kInverse.Inverse(&k)
+	a.ScalarMultiplication(&a, k.BigInt(new(big.Int)))
+	b.ScalarMultiplication(&b, kInverse.BigInt(new(big.Int)))
+
+	rerandomized := types.ZKProof{
+		Proof: &types.ProofData{
+			A:        []string{a.X.String(), a.Y.String(), "1"},
+			B:        [][]string{{b.X.A0.String(), b.X.A1.String()}, {b.Y.A0.String(), b.Y.A1.String()}, {"1", "0"}},
+			C:        []string{c.X.String(), c.Y.String(), "1"},
+			Protocol: "groth16",
+		},
+		PubSignals: proof.PubSignals,
+	}
+	if err := vk.Verify(rerandomized); err != nil {
+		t.Fatalf("rerandomized proof rejected: %v", err)
+	}
+
+	signedAttributes := []byte("signed attributes")
+	hash, err := StatementHash(signedAttributes, proof.PubSignals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rerandomizedHash, err := StatementHash(signedAttributes, rerandomized.PubSignals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(hash) != string(rerandomizedHash) {
+		t.Error("rerandomized proof statement hashed differently")
+	}
+
+	otherHash, err := StatementHash([]byte("other signed attributes"), proof.PubSignals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(hash) == string(otherHash) {
+		t.Error("statements of the different documents hashed the same")
+	}
+}
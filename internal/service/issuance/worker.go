@@ -0,0 +1,397 @@
+package issuance
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+)
+
+// errCancelled is returned once the claim request is not pending anymore, the revocation of
+// its document fails the pending claim requests.
+var errCancelled = errors.New("claim request is cancelled")
+
+// BlinderSource provides the blinder of the credential hash.
+type BlinderSource interface {
+	Blinder() (*big.Int, error)
+}
+
+// Worker issues the claims of the claim requests the registrations commit. Every attempt
+// leases the request first, so the workers of the service replicas do not issue it twice,
+// and records the issued claim ID before the claim, so a retry only records the claim.
+// Only one claim request of a document is leased at a time, so that the claims of the
+// document are revoked and issued in turn, and the claim ID is recorded within the lease.
+type Worker struct {
+	log      *logan.Entry
+	cfg      *config.IssuanceConfig
+	db       data.MasterQ
+	issuer   issuer.Issuer
+	blinders BlinderSource
+}
+
+func NewWorker(
+	log *logan.Entry, cfg *config.IssuanceConfig, db data.MasterQ, iss issuer.Issuer, blinders BlinderSource,
+) *Worker {
+	return &Worker{
+		log:      log,
+		cfg:      cfg,
+		db:       db,
+		issuer:   iss,
+		blinders: blinders,
+	}
+}
+
+// Run processes the due claim requests every poll period until the context is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.drain(ctx)
+		}
+	}
+}
+
+// drain processes the due claim requests until there are none left.
+func (w *Worker) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		request, err := w.lease(time.Now().UTC())
+		if err != nil {
+			w.log.WithError(err).Error("failed to lease claim request")
+			return
+		}
+		if request == nil {
+			return
+		}
+
+		w.process(*request)
+	}
+}
+
+// lease takes the oldest due claim request and postpones its next attempt by the lease,
+// it returns nil if there is none. The document lock makes the workers lease the claim
+// requests of the same document one after another, so the one leased first is seen here.
+func (w *Worker) lease(now time.Time) (*data.ClaimRequest, error) {
+	var leased *data.ClaimRequest
+	err := w.db.New().Transaction(func(db data.MasterQ) error {
+		request, err := db.ClaimRequest().FilterDue(now).ForUpdateSkipLocked().Get()
+		if err != nil {
+			return errors.Wrap(err, "failed to get due claim request")
+		}
+		if request == nil {
+			return nil
+		}
+
+		if err := db.ClaimRequest().LockDocument(request.DocumentHash); err != nil {
+			return errors.Wrap(err, "failed to lock document claim requests")
+		}
+
+		sibling, err := db.ClaimRequest().FilterBy("document_hash", request.DocumentHash).FilterLeased(now).Get()
+		if err != nil {
+			return errors.Wrap(err, "failed to get leased document claim request")
+		}
+		if sibling != nil {
+			// it is leased again once the sibling lease is over
+			return nil
+		}
+
+		leasedUntil := now.Add(w.cfg.Lease)
+		request.Attempts++
+		request.NextAttemptAt = leasedUntil
+		request.LeasedUntil = &leasedUntil
+		request.UpdatedAt = now
+		if err := db.ClaimRequest().Update(*request); err != nil {
+			return errors.Wrap(err, "failed to update claim request")
+		}
+
+		leased = request
+		return nil
+	})
+
+	return leased, err
+}
+
+func (w *Worker) process(request data.ClaimRequest) {
+	log := w.log.WithFields(logan.F{
+		"claim_request": request.ID,
+		"attempt":       request.Attempts,
+	})
+
+	err := w.issue(&request)
+	if err == nil {
+		log.WithField("claim_id", request.ClaimID).Info("claim issued")
+		return
+	}
+	if request.ClaimID != nil {
+		log = log.WithField("claim_id", request.ClaimID)
+	}
+	if errors.Cause(err) == errCancelled {
+		log.Info("claim request cancelled")
+		return
+	}
+
+	if retryErr := w.retry(request, err, time.Now().UTC()); retryErr != nil {
+		if errors.Cause(retryErr) == errCancelled {
+			log.WithError(err).Warn("claim request cancelled after failed attempt")
+			return
+		}
+		log.WithError(retryErr).Error("failed to reschedule claim request")
+		return
+	}
+	log.WithError(err).Error("failed to issue claim")
+}
+
+func (w *Worker) issue(request *data.ClaimRequest) error {
+	if request.ClaimID == nil {
+		if err := w.revokeOutdatedClaims(*request); err != nil {
+			return errors.Wrap(err, "failed to revoke outdated claims")
+		}
+
+		blinder, err := w.blinders.Blinder()
+		if err != nil {
+			return errors.Wrap(err, "failed to get blinder")
+		}
+
+		rawClaimID, err := w.issuer.IssueVotingClaim(
+			request.UserDID, request.IssuingAuthority, request.IsAdult, request.Expiration,
+			request.DG2Hash, blinder, request.UserAddress, request.UserID, request.DocumentHash,
+		)
+		if err != nil {
+			return errors.Wrap(err, "failed to issue voting claim")
+		}
+
+		claimID, err := uuid.Parse(rawClaimID)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse claim ID")
+		}
+
+		issuerDID := w.issuer.DID()
+		request.ClaimID = &claimID
+		request.IssuerDID = &issuerDID
+		request.UpdatedAt = time.Now().UTC()
+		err = w.db.New().Transaction(func(db data.MasterQ) error {
+			return updatePending(db, *request)
+		})
+		if errors.Cause(err) == errCancelled {
+			return w.revokeCancelled(*request)
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to record issued claim ID")
+		}
+	}
+
+	// the credential status is recorded with the claim, so the claims are listed without
+	// asking the issuer
+	cred, err := w.issuer.GetCredential(*request.ClaimID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get issued credential")
+	}
+	var expiresAt *time.Time
+	if !cred.ExpiresAt.IsZero() {
+		expiresAt = &cred.ExpiresAt
+	}
+
+	// the request stays pending if the claim is not recorded
+	issued := *request
+	issued.Status = data.ClaimRequestIssued
+	issued.LeasedUntil = nil
+	issued.LastError = nil
+	issued.UpdatedAt = time.Now().UTC()
+
+	return w.db.New().Transaction(func(db data.MasterQ) error {
+		if err := db.Claim().Insert(data.Claim{
+			ID:           *issued.ClaimID,
+			UserDID:      issued.UserDID,
+			UserID:       issued.UserID,
+			UserAddress:  issued.UserAddress,
+			IssuerDID:    *issued.IssuerDID,
+			DocumentHash: issued.DocumentHash,
+
+			ActiveAuthentication: issued.ActiveAuthentication,
+			ChipAuthentication:   issued.ChipAuthentication,
+			Policy:               issued.Policy,
+			DSSerial:             issued.DSSerial,
+			DSCountry:            issued.DSCountry,
+			Status:               data.ClaimActive,
+			RevocationNonce:      &cred.CredentialStatus.RevocationNonce,
+			ExpiresAt:            expiresAt,
+		}); err != nil {
+			return errors.Wrap(err, "failed to insert claim")
+		}
+
+		// the claim request cancelled meanwhile has its claim revoked by the revocation
+		return updatePending(db, issued)
+	})
+}
+
+// revokeCancelled revokes the claim issued for the claim request the revocation has cancelled
+// meanwhile. The claim ID is recorded on the failed claim request first, so the claim is
+// revoked by the next revocation of the document if it fails here.
+func (w *Worker) revokeCancelled(request data.ClaimRequest) error {
+	cancelled, err := w.db.New().ClaimRequest().FilterBy("id", request.ID).Get()
+	if err != nil {
+		return errors.Wrap(err, "failed to get cancelled claim request")
+	}
+	if cancelled == nil {
+		return errors.New("cancelled claim request not found")
+	}
+
+	cancelled.ClaimID = request.ClaimID
+	cancelled.IssuerDID = request.IssuerDID
+	cancelled.UpdatedAt = time.Now().UTC()
+	if err := w.db.New().ClaimRequest().Update(*cancelled); err != nil {
+		return errors.Wrap(err, "failed to record claim ID of cancelled claim request")
+	}
+
+	if err := w.revokeClaim(*request.ClaimID); err != nil {
+		return errors.Wrap(err, "failed to revoke claim of cancelled claim request")
+	}
+
+	return errCancelled
+}
+
+// updatePending updates the claim request under the document lock unless it is not pending
+// anymore, as the revocation of the document fails the pending claim requests under the same
+// lock.
+func updatePending(db data.MasterQ, request data.ClaimRequest) error {
+	if err := db.ClaimRequest().LockDocument(request.DocumentHash); err != nil {
+		return errors.Wrap(err, "failed to lock document claim requests")
+	}
+
+	current, err := db.ClaimRequest().FilterBy("id", request.ID).Get()
+	if err != nil {
+		return errors.Wrap(err, "failed to get claim request")
+	}
+	if current == nil || current.Status != data.ClaimRequestPending {
+		return errCancelled
+	}
+
+	if err := db.ClaimRequest().Update(request); err != nil {
+		return errors.Wrap(err, "failed to update claim request")
+	}
+
+	return nil
+}
+
+// revokeOutdatedClaims revokes the claims previously issued for the document and records
+// them as superseded.
+// The claims issued for the other claim requests of the document, but not recorded yet, are
+// live as well, so they are revoked too and their pending requests are failed.
+func (w *Worker) revokeOutdatedClaims(request data.ClaimRequest) error {
+	claims, err := w.db.New().Claim().FilterBy("document_hash", request.DocumentHash).Select()
+	if err != nil {
+		return errors.Wrap(err, "failed to select claims")
+	}
+
+	for _, claim := range claims {
+		if claim.Status != data.ClaimActive {
+			continue
+		}
+
+		if err := w.revokeClaim(claim.ID); err != nil {
+			return err
+		}
+
+		claim.Status = data.ClaimSuperseded
+		if err := w.db.New().Claim().Update(claim); err != nil {
+			return errors.Wrap(err, "failed to record superseded claim", logan.F{"claim_id": claim.ID})
+		}
+	}
+
+	unrecorded, err := w.db.New().ClaimRequest().FilterBy("document_hash", request.DocumentHash).FilterUnrecorded().Select()
+	if err != nil {
+		return errors.Wrap(err, "failed to select unrecorded claim requests")
+	}
+
+	for _, sibling := range unrecorded {
+		if sibling.ID == request.ID {
+			continue
+		}
+
+		if err := w.revokeClaim(*sibling.ClaimID); err != nil {
+			return err
+		}
+
+		if sibling.Status != data.ClaimRequestPending {
+			continue
+		}
+
+		lastError := "superseded by claim request " + request.ID.String()
+		sibling.Status = data.ClaimRequestFailed
+		sibling.LastError = &lastError
+		sibling.UpdatedAt = time.Now().UTC()
+		if err := w.db.New().ClaimRequest().Update(sibling); err != nil {
+			return errors.Wrap(err, "failed to fail superseded claim request", logan.F{"claim_request": sibling.ID})
+		}
+	}
+
+	return nil
+}
+
+// revokeClaim revokes the claim unless it is revoked already.
+func (w *Worker) revokeClaim(claimID uuid.UUID) error {
+	cred, err := w.issuer.GetCredential(claimID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get credential", logan.F{"claim_id": claimID})
+	}
+
+	if cred.Revoked {
+		return nil
+	}
+
+	if err := w.issuer.RevokeClaim(cred.CredentialStatus.RevocationNonce); err != nil {
+		return errors.Wrap(err, "failed to revoke claim", logan.F{"claim_id": claimID})
+	}
+
+	return nil
+}
+
+// retry schedules the next attempt of the claim request with the exponential backoff, or
+// fails it once the attempts are exhausted or the issuer node has rejected it.
+func (w *Worker) retry(request data.ClaimRequest, cause error, now time.Time) error {
+	lastError := cause.Error()
+	request.LastError = &lastError
+	request.LeasedUntil = nil
+	request.UpdatedAt = now
+
+	if request.Attempts >= w.cfg.MaxAttempts || !retryable(cause) {
+		request.Status = data.ClaimRequestFailed
+	} else {
+		request.NextAttemptAt = now.Add(w.backoff(request.Attempts))
+	}
+
+	return w.db.New().Transaction(func(db data.MasterQ) error {
+		return updatePending(db, request)
+	})
+}
+
+// backoff doubles the min backoff with every attempt up to the max backoff.
+func (w *Worker) backoff(attempts int) time.Duration {
+	backoff := w.cfg.MinBackoff
+	for i := 1; i < attempts && backoff < w.cfg.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > w.cfg.MaxBackoff {
+		backoff = w.cfg.MaxBackoff
+	}
+
+	return backoff
+}
+
+// retryable reports whether the attempt may succeed later, the issuer node rejecting the
+// request does not change on retry.
+func retryable(err error) bool {
+	_, rejected := errors.Cause(err).(*issuer.NodeError)
+	return !rejected || issuer.Unavailable(err)
+}
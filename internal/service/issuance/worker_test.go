@@ -0,0 +1,338 @@
+package issuance
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/iden3/go-iden3-core/v2/w3c"
+	"gitlab.com/distributed_lab/logan/v3"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/data/datatest"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/internal/service/revocation"
+)
+
+const testIssuerDID = "did:iden3:readonly:tJWarsbwqiUxHm8BPi4aYSnnj54AbuR4D2RrhkykQ"
+
+type staticBlinder struct{}
+
+func (staticBlinder) Blinder() (*big.Int, error) {
+	return big.NewInt(42), nil
+}
+
+// countingIssuer counts the claims issued.
+type countingIssuer struct {
+	issuer.Issuer
+	issued int
+}
+
+func (is *countingIssuer) IssueVotingClaim(
+	id string, issuingAuthority int64, isAdult bool, expiration *time.Time, dg2 []byte,
+	blinder *big.Int, userAddress common.Address, userID uuid.UUID, documentHash string,
+) (string, error) {
+	is.issued++
+	return is.Issuer.IssueVotingClaim(id, issuingAuthority, isAdult, expiration, dg2, blinder, userAddress, userID, documentHash)
+}
+
+// failingClaims fails the claim inserts, as the failed commit after the issuance does.
+type failingClaims struct {
+	data.MasterQ
+}
+
+func (q failingClaims) New() data.MasterQ {
+	return failingClaims{q.MasterQ.New()}
+}
+
+func (q failingClaims) Claim() data.ClaimQ {
+	return failingClaimQ{q.MasterQ.Claim()}
+}
+
+func (q failingClaims) Transaction(fn func(db data.MasterQ) error) error {
+	return q.MasterQ.Transaction(func(db data.MasterQ) error {
+		return fn(failingClaims{db})
+	})
+}
+
+type failingClaimQ struct {
+	data.ClaimQ
+}
+
+func (failingClaimQ) Insert(data.Claim) error {
+	return errors.New("connection reset")
+}
+
+func newTestWorker(t *testing.T, db data.MasterQ) (*Worker, *countingIssuer) {
+	t.Helper()
+
+	did, err := w3c.ParseDID(testIssuerDID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iss := &countingIssuer{Issuer: issuer.NewMemory(&config.IssuerConfig{DID: did, ClaimType: "VotingCredential"})}
+	cfg := &config.IssuanceConfig{
+		PollPeriod:  time.Second,
+		MaxAttempts: 3,
+		MinBackoff:  time.Second,
+		MaxBackoff:  3 * time.Second,
+		Lease:       time.Minute,
+	}
+
+	return NewWorker(logan.New(), cfg, db, iss, staticBlinder{}), iss
+}
+
+func newClaimRequest(db *datatest.DB, documentHash string, due time.Time) uuid.UUID {
+	expiration := due.Add(24 * time.Hour)
+	request := data.ClaimRequest{
+		ID:            uuid.New(),
+		Status:        data.ClaimRequestPending,
+		UserID:        uuid.New(),
+		UserDID:       testIssuerDID,
+		DocumentHash:  documentHash,
+		Expiration:    &expiration,
+		DG2Hash:       []byte{1},
+		Policy:        "default",
+		NextAttemptAt: due,
+		CreatedAt:     due,
+	}
+	db.ClaimRequests[request.ID] = request
+
+	return request.ID
+}
+
+func TestWorkerRecordsClaimAfterFailedCommit(t *testing.T) {
+	db := datatest.New()
+	now := time.Now().UTC()
+	id := newClaimRequest(db, "1", now)
+
+	worker, iss := newTestWorker(t, failingClaims{db.MasterQ()})
+	request, err := worker.lease(now)
+	if err != nil || request == nil {
+		t.Fatalf("failed to lease claim request: %v", err)
+	}
+	worker.process(*request)
+
+	failed := db.ClaimRequests[id]
+	if failed.Status != data.ClaimRequestPending || failed.ClaimID == nil || failed.LastError == nil || failed.LeasedUntil != nil {
+		t.Fatalf("expected pending claim request with claim ID recorded, got %+v", failed)
+	}
+
+	worker.db = db.MasterQ()
+	request, err = worker.lease(failed.NextAttemptAt)
+	if err != nil || request == nil {
+		t.Fatalf("failed to lease claim request: %v", err)
+	}
+	worker.process(*request)
+
+	issued := db.ClaimRequests[id]
+	if issued.Status != data.ClaimRequestIssued || *issued.ClaimID != *failed.ClaimID || issued.LeasedUntil != nil {
+		t.Fatalf("expected issued claim request, got %+v", issued)
+	}
+	if _, ok := db.Claims[*issued.ClaimID]; !ok {
+		t.Fatal("claim is not recorded")
+	}
+	if iss.issued != 1 {
+		t.Fatalf("expected claim issued once, issued %d times", iss.issued)
+	}
+}
+
+func TestWorkerLeasesDocumentOnce(t *testing.T) {
+	db := datatest.New()
+	now := time.Now().UTC()
+	first := newClaimRequest(db, "1", now.Add(-time.Second))
+	second := newClaimRequest(db, "1", now)
+	other := newClaimRequest(db, "2", now)
+
+	worker, _ := newTestWorker(t, db.MasterQ())
+	request, err := worker.lease(now)
+	if err != nil || request == nil || request.ID != first {
+		t.Fatalf("expected first claim request leased, got %+v: %v", request, err)
+	}
+
+	request, err = worker.lease(now)
+	if err != nil || request == nil || request.ID != other {
+		t.Fatalf("expected the claim request of the other document leased, got %+v: %v", request, err)
+	}
+
+	request, err = worker.lease(now)
+	if err != nil || request != nil {
+		t.Fatalf("expected no claim request leased while the document is, got %+v: %v", request, err)
+	}
+
+	// the lease of the dead worker is over
+	request, err = worker.lease(now.Add(2 * time.Minute))
+	if err != nil || request == nil || request.ID == other {
+		t.Fatalf("expected the document claim request leased after the lease, got %+v: %v", request, err)
+	}
+	if request.ID != first && request.ID != second {
+		t.Fatalf("unexpected claim request %s leased", request.ID)
+	}
+}
+
+func TestWorkerRevokesUnrecordedClaims(t *testing.T) {
+	db := datatest.New()
+	now := time.Now().UTC()
+	superseded := newClaimRequest(db, "1", now.Add(time.Hour))
+	recorded := newClaimRequest(db, "1", now.Add(-time.Second))
+
+	worker, iss := newTestWorker(t, db.MasterQ())
+
+	// the claim of the superseded request was issued, but the commit recording it failed
+	rawClaimID, err := iss.IssueVotingClaim(testIssuerDID, 0, true, nil, []byte{1}, big.NewInt(1), common.Address{}, uuid.New(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unrecordedID := uuid.MustParse(rawClaimID)
+	request := db.ClaimRequests[superseded]
+	request.ClaimID = &unrecordedID
+	db.ClaimRequests[superseded] = request
+
+	leased, err := worker.lease(now)
+	if err != nil || leased == nil || leased.ID != recorded {
+		t.Fatalf("expected claim request leased, got %+v: %v", leased, err)
+	}
+	worker.process(*leased)
+
+	if status := db.ClaimRequests[recorded].Status; status != data.ClaimRequestIssued {
+		t.Fatalf("expected claim request issued, got %s", status)
+	}
+
+	cred, err := iss.GetCredential(unrecordedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cred.Revoked {
+		t.Error("unrecorded claim is not revoked")
+	}
+	if status := db.ClaimRequests[superseded].Status; status != data.ClaimRequestFailed {
+		t.Errorf("expected superseded claim request failed, got %s", status)
+	}
+}
+
+func TestWorkerSupersedesDocumentClaims(t *testing.T) {
+	db := datatest.New()
+	now := time.Now().UTC()
+	first := newClaimRequest(db, "1", now.Add(-time.Second))
+	second := newClaimRequest(db, "1", now)
+
+	worker, iss := newTestWorker(t, db.MasterQ())
+	for range []uuid.UUID{first, second} {
+		request, err := worker.lease(now)
+		if err != nil || request == nil {
+			t.Fatalf("failed to lease claim request: %v", err)
+		}
+		worker.process(*request)
+	}
+
+	superseded, ok := db.Claims[*db.ClaimRequests[first].ClaimID]
+	if !ok || superseded.Status != data.ClaimSuperseded {
+		t.Fatalf("expected the first claim kept as superseded, got %+v", superseded)
+	}
+	cred, err := iss.GetCredential(superseded.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cred.Revoked {
+		t.Error("superseded claim is not revoked")
+	}
+
+	active := db.Claims[*db.ClaimRequests[second].ClaimID]
+	if active.Status != data.ClaimActive || active.RevocationNonce == nil || active.ExpiresAt == nil {
+		t.Fatalf("expected active claim with the credential status recorded, got %+v", active)
+	}
+}
+
+func TestWorkerSkipsRevokedClaimRequests(t *testing.T) {
+	db := datatest.New()
+	now := time.Now().UTC()
+	pending := newClaimRequest(db, "1", now)
+	withSerial := db.ClaimRequests[pending]
+	withSerial.DSSerial, withSerial.DSCountry = "a1b", "UA"
+	db.ClaimRequests[pending] = withSerial
+	leased := newClaimRequest(db, "2", now.Add(-time.Second))
+
+	worker, iss := newTestWorker(t, db.MasterQ())
+	revoker := revocation.NewRevoker(db.MasterQ(), iss)
+
+	// the worker has leased the claim request of the second document before its revocation
+	request, err := worker.lease(now)
+	if err != nil || request == nil || request.ID != leased {
+		t.Fatalf("expected claim request leased, got %+v: %v", request, err)
+	}
+
+	serial, documentHash := "0a:1b", "2"
+	revocations, err := revoker.Revoke(revocation.Selector{DSSerial: &serial}, revocation.ReasonCompromisedCSCA, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revocations) != 1 || *revocations[0].ClaimRequestID != pending || revocations[0].ClaimID != nil {
+		t.Fatalf("expected pending claim request revoked, got %+v", revocations)
+	}
+	revocations, err = revoker.Revoke(revocation.Selector{DocumentHash: &documentHash}, revocation.ReasonFraud, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revocations) != 1 || *revocations[0].ClaimRequestID != leased {
+		t.Fatalf("expected leased claim request revoked, got %+v", revocations)
+	}
+
+	worker.process(*request)
+	if next, err := worker.lease(now.Add(2 * time.Minute)); err != nil || next != nil {
+		t.Fatalf("expected no claim request leased after the revocation, got %+v: %v", next, err)
+	}
+
+	if iss.issued != 1 || len(db.Claims) != 0 {
+		t.Fatalf("expected only the leased claim issued and none recorded, issued %d, recorded %d", iss.issued, len(db.Claims))
+	}
+	for _, id := range []uuid.UUID{pending, leased} {
+		if status := db.ClaimRequests[id].Status; status != data.ClaimRequestFailed {
+			t.Errorf("expected claim request %s failed, got %s", id, status)
+		}
+	}
+
+	cancelled := db.ClaimRequests[leased]
+	if cancelled.ClaimID == nil {
+		t.Fatal("claim ID of the cancelled claim request is not recorded")
+	}
+	cred, err := iss.GetCredential(*cancelled.ClaimID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cred.Revoked {
+		t.Error("claim issued for the cancelled claim request is not revoked")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	worker, _ := newTestWorker(t, datatest.New().MasterQ())
+
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		if got := worker.backoff(i + 1); got != want {
+			t.Errorf("attempt %d: expected %s backoff, got %s", i+1, want, got)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	cases := map[string]struct {
+		err  error
+		want bool
+	}{
+		"rejected":     {&issuer.NodeError{StatusCode: 400}, false},
+		"rate limited": {&issuer.NodeError{StatusCode: 429}, true},
+		"unavailable":  {&issuer.NodeError{StatusCode: 503}, true},
+		"network":      {errors.New("connection refused"), true},
+	}
+
+	for name, c := range cases {
+		if got := retryable(c.err); got != c.want {
+			t.Errorf("%s: expected retryable %t, got %t", name, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package issuer
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker is the circuit breaker of the issuer node calls: once threshold calls in a row
+// fail it rejects the calls for the cooldown, then lets a single probe call through, which
+// either closes it or opens it again. The zero threshold disables it.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow returns ErrIssuerUnavailable while the breaker is open.
+func (b *breaker) allow() error {
+	if b.threshold == 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return ErrIssuerUnavailable
+	}
+
+	b.probing = true
+	return nil
+}
+
+// done records the outcome of the allowed call.
+func (b *breaker) done(healthy bool) {
+	if b.threshold == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if healthy {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
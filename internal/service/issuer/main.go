@@ -3,6 +3,7 @@ package issuer
 import (
 	"fmt"
 	"math/big"
+	"net/http"
 	"strconv"
 	"time"
 
@@ -17,29 +18,52 @@ import (
 	"github.com/rarimo/passport-identity-provider/internal/config"
 )
 
-type Issuer struct {
-	log    *logan.Entry
-	client *req.Client
-	cfg    *config.IssuerConfig
-	did    string
+// Issuer issues and revokes the voting claims.
+type Issuer interface {
+	DID() string
+	IssueVotingClaim(
+		id string,
+		issuingAuthority int64,
+		isAdult bool,
+		expiration *time.Time,
+		dg2 []byte,
+		blinder *big.Int,
+		userAddress common.Address,
+		userId uuid.UUID,
+		documentHash string,
+	) (string, error)
+	GetCredential(claimID uuid.UUID) (GetCredentialResponse, error)
+	RevokeClaim(revocationNonce int64) error
 }
 
-func New(log *logan.Entry, config *config.IssuerConfig, login, password string) *Issuer {
-	return &Issuer{
+// Client is the Issuer of the remote iden3 issuer node. The safe and idempotent calls
+// are retried with the jittered backoff, all of them go through the circuit breaker.
+type Client struct {
+	log     *logan.Entry
+	client  *req.Client
+	cfg     *config.IssuerConfig
+	did     string
+	breaker *breaker
+}
+
+func New(log *logan.Entry, config *config.IssuerConfig, login, password string) *Client {
+	return &Client{
 		client: req.C().
 			SetBaseURL(fmt.Sprintf("%s/%s", config.BaseUrl, config.DID.String())).
 			SetCommonBasicAuth(login, password).
+			SetTimeout(config.Timeout).
 			SetLogger(log),
-		cfg: config,
-		did: config.DID.String(),
+		cfg:     config,
+		did:     config.DID.String(),
+		breaker: newBreaker(config.BreakerThreshold, config.BreakerCooldown),
 	}
 }
 
-func (is *Issuer) DID() string {
+func (is *Client) DID() string {
 	return is.did
 }
 
-func (is *Issuer) IssueVotingClaim(
+func (is *Client) IssueVotingClaim(
 	id string,
 	issuingAuthority int64,
 	isAdult bool,
@@ -52,6 +76,105 @@ func (is *Issuer) IssueVotingClaim(
 ) (string, error) {
 	var result UUIDResponse
 
+	credentialRequest, err := newVotingCredentialRequest(
+		is.cfg, id, issuingAuthority, isAdult, expiration, dg2, blinder, userAddress, userId, documentHash,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	// every call issues a new claim, so it is not retried
+	if err := is.send(is.client.R().
+		SetBodyJsonMarshal(credentialRequest).
+		SetSuccessResult(&result),
+		http.MethodPost, "/claims",
+	); err != nil {
+		return "", errors.Wrap(err, "failed to create claim")
+	}
+
+	return result.Id, nil
+}
+
+func (is *Client) GetCredential(claimID uuid.UUID) (GetCredentialResponse, error) {
+	var cred GetCredentialResponse
+
+	if err := is.send(is.retried(is.client.R()).
+		SetSuccessResult(&cred).
+		SetPathParam("id", claimID.String()),
+		http.MethodGet, "/claims/{id}",
+	); err != nil {
+		return GetCredentialResponse{}, errors.Wrap(err, "failed to get claim")
+	}
+
+	return cred, nil
+}
+
+func (is *Client) RevokeClaim(revocationNonce int64) error {
+	// revoking the nonce again changes nothing, so it is retried
+	if err := is.send(is.retried(is.client.R()).
+		SetPathParam("nonce", strconv.FormatInt(revocationNonce, 10)),
+		http.MethodPost, "/claims/revoke/{nonce}",
+	); err != nil {
+		return errors.Wrap(err, "failed to revoke claim")
+	}
+
+	return nil
+}
+
+// retried enables the retries of the request on the network errors and the issuer node failures.
+func (is *Client) retried(request *req.Request) *req.Request {
+	return request.
+		SetRetryCount(is.cfg.RetryAttempts).
+		SetRetryBackoffInterval(is.cfg.RetryMinBackoff, is.cfg.RetryMaxBackoff).
+		SetRetryCondition(func(response *req.Response, err error) bool {
+			return response.GetStatusCode() == 0 || unhealthy(response.GetStatusCode())
+		})
+}
+
+// send sends the request through the circuit breaker and parses the issuer node errors.
+func (is *Client) send(request *req.Request, method, url string) error {
+	if err := is.breaker.allow(); err != nil {
+		return err
+	}
+
+	var errResp errorResponse
+	response, err := request.SetErrorResult(&errResp).Send(method, url)
+
+	// there is no status code if the node has not responded at all
+	statusCode := response.GetStatusCode()
+	is.breaker.done(statusCode != 0 && !unhealthy(statusCode))
+	if statusCode == 0 {
+		return errors.Wrap(ErrIssuerUnavailable, fmt.Sprint(err))
+	}
+
+	if statusCode >= 299 {
+		message := errResp.Message
+		if message == "" {
+			message = response.String()
+		}
+		return &NodeError{StatusCode: statusCode, Message: message}
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "failed to parse issuer node response")
+	}
+
+	return nil
+}
+
+// newVotingCredentialRequest builds the voting credential of the registration.
+func newVotingCredentialRequest(
+	cfg *config.IssuerConfig,
+	id string,
+	issuingAuthority int64,
+	isAdult bool,
+	expiration *time.Time,
+	dg2 []byte,
+	blinder *big.Int,
+	userAddress common.Address,
+	userId uuid.UUID,
+	documentHash string,
+) (CredentialRequest, error) {
 	nullifierHashInput := make([]*big.Int, 0)
 	if len(dg2) >= 32 {
 		// break data in a half
@@ -64,7 +187,7 @@ func (is *Issuer) IssueVotingClaim(
 
 	nullifierHash, err := poseidon.Hash(nullifierHashInput)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to hash bytes")
+		return CredentialRequest{}, errors.Wrap(err, "failed to hash bytes")
 	}
 
 	credHashInput := make([]*big.Int, 0)
@@ -74,12 +197,12 @@ func (is *Issuer) IssueVotingClaim(
 
 	credentialHash, err := poseidon.Hash(credHashInput)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to hash bytes")
+		return CredentialRequest{}, errors.Wrap(err, "failed to hash bytes")
 	}
 
-	credentialRequest := CredentialRequest{
-		CredentialSchema: is.cfg.CredentialSchema,
-		Type:             is.cfg.ClaimType,
+	return CredentialRequest{
+		CredentialSchema: cfg.CredentialSchema,
+		Type:             cfg.ClaimType,
 		CredentialSubject: CredentialSubject{
 			ID:                id,
 			IssuingAuthority:  issuingAuthority,
@@ -91,54 +214,22 @@ func (is *Issuer) IssueVotingClaim(
 			Metadata:          "_",
 			Features:          documentHash,
 		},
+		Expiration:     credentialExpiration(cfg, expiration, time.Now().UTC()),
 		MtProof:        true,
 		SignatureProof: true,
-	}
-
-	response, err := is.client.R().
-		SetBodyJsonMarshal(credentialRequest).
-		SetSuccessResult(&result).
-		Post(fmt.Sprintf("/claims"))
-	if err != nil {
-		return "", errors.Wrap(err, "failed to send post request")
-	}
-
-	if response.StatusCode >= 299 {
-		return "", errors.Wrap(ErrUnexpectedStatusCode, response.String())
-	}
-
-	return result.Id, nil
+	}, nil
 }
 
-func (is *Issuer) GetCredential(claimID uuid.UUID) (GetCredentialResponse, error) {
-	var cred GetCredentialResponse
-
-	response, err := is.client.R().
-		SetSuccessResult(&cred).
-		SetPathParam("id", claimID.String()).
-		Get("/claims/{id}")
-	if err != nil {
-		return GetCredentialResponse{}, errors.Wrap(err, "failed to send post request")
+// credentialExpiration is the earlier of the document expiration and the maximum credential lifetime.
+func credentialExpiration(cfg *config.IssuerConfig, documentExpiration *time.Time, now time.Time) *time.Time {
+	if cfg.MaxCredentialLifetime == 0 {
+		return documentExpiration
 	}
 
-	if response.StatusCode >= 299 {
-		return GetCredentialResponse{}, errors.Wrap(ErrUnexpectedStatusCode, response.String())
+	maxExpiration := now.Add(cfg.MaxCredentialLifetime)
+	if documentExpiration != nil && documentExpiration.Before(maxExpiration) {
+		return documentExpiration
 	}
 
-	return cred, nil
-}
-
-func (is *Issuer) RevokeClaim(revocationNonce int64) error {
-	response, err := is.client.R().
-		SetPathParam("nonce", strconv.FormatInt(revocationNonce, 10)).
-		Post("/claims/revoke/{nonce}")
-	if err != nil {
-		return errors.Wrap(err, "failed to send post request")
-	}
-
-	if response.StatusCode >= 299 {
-		return errors.Wrap(ErrUnexpectedStatusCode, response.String())
-	}
-
-	return nil
+	return &maxExpiration
 }
@@ -0,0 +1,164 @@
+package issuer
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+)
+
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// proof types of the issuer node credentials
+const (
+	proofTypeSignature = "BJJSignature2021"
+	proofTypeMTP       = "Iden3SparseMerkleTreeProof"
+)
+
+// MemoryIssuer is the in-process Issuer for the offline runs and tests. It keeps the
+// credentials in memory as the JSON the issuer node accepts and returns, so the same
+// payloads go through it.
+type MemoryIssuer struct {
+	cfg *config.IssuerConfig
+	did string
+
+	mu          sync.Mutex
+	credentials map[uuid.UUID][]byte
+	nonces      map[int64]uuid.UUID
+	nextNonce   int64
+}
+
+func NewMemory(config *config.IssuerConfig) *MemoryIssuer {
+	return &MemoryIssuer{
+		cfg:         config,
+		did:         config.DID.String(),
+		credentials: make(map[uuid.UUID][]byte),
+		nonces:      make(map[int64]uuid.UUID),
+	}
+}
+
+func (is *MemoryIssuer) DID() string {
+	return is.did
+}
+
+func (is *MemoryIssuer) IssueVotingClaim(
+	id string,
+	issuingAuthority int64,
+	isAdult bool,
+	expiration *time.Time,
+	dg2 []byte,
+	blinder *big.Int,
+	userAddress common.Address,
+	userId uuid.UUID,
+	documentHash string,
+) (string, error) {
+	credentialRequest, err := newVotingCredentialRequest(
+		is.cfg, id, issuingAuthority, isAdult, expiration, dg2, blinder, userAddress, userId, documentHash,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	// the request goes through JSON the same way it is sent to the issuer node
+	rawRequest, err := json.Marshal(credentialRequest)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal credential request")
+	}
+
+	var request struct {
+		CredentialSchema  string          `json:"credentialSchema"`
+		Type              string          `json:"type"`
+		CredentialSubject json.RawMessage `json:"credentialSubject"`
+		Expiration        *time.Time      `json:"expiration"`
+		MtProof           bool            `json:"mtProof"`
+		SignatureProof    bool            `json:"signatureProof"`
+	}
+	if err := json.Unmarshal(rawRequest, &request); err != nil {
+		return "", errors.Wrap(err, "failed to unmarshal credential request")
+	}
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	claimID := uuid.New()
+	is.nextNonce++
+
+	credential := GetCredentialResponse{
+		Id:                claimID.String(),
+		ProofTypes:        make([]string, 0, 2),
+		CreatedAt:         time.Now().UTC(),
+		SchemaType:        request.Type,
+		SchemaUrl:         request.CredentialSchema,
+		CredentialStatus:  CredentialStatus{RevocationNonce: is.nextNonce},
+		CredentialSubject: request.CredentialSubject,
+		UserID:            id,
+	}
+	if request.SignatureProof {
+		credential.ProofTypes = append(credential.ProofTypes, proofTypeSignature)
+	}
+	if request.MtProof {
+		credential.ProofTypes = append(credential.ProofTypes, proofTypeMTP)
+	}
+	if request.Expiration != nil {
+		credential.ExpiresAt = *request.Expiration
+	}
+
+	rawCredential, err := json.Marshal(credential)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal credential")
+	}
+
+	is.credentials[claimID] = rawCredential
+	is.nonces[credential.CredentialStatus.RevocationNonce] = claimID
+
+	return claimID.String(), nil
+}
+
+func (is *MemoryIssuer) GetCredential(claimID uuid.UUID) (GetCredentialResponse, error) {
+	is.mu.Lock()
+	rawCredential, ok := is.credentials[claimID]
+	is.mu.Unlock()
+	if !ok {
+		return GetCredentialResponse{}, errors.Wrap(ErrCredentialNotFound, claimID.String())
+	}
+
+	var cred GetCredentialResponse
+	if err := json.Unmarshal(rawCredential, &cred); err != nil {
+		return GetCredentialResponse{}, errors.Wrap(err, "failed to unmarshal credential")
+	}
+
+	cred.Expired = !cred.ExpiresAt.IsZero() && !time.Now().Before(cred.ExpiresAt)
+
+	return cred, nil
+}
+
+func (is *MemoryIssuer) RevokeClaim(revocationNonce int64) error {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	claimID, ok := is.nonces[revocationNonce]
+	if !ok {
+		return errors.Wrap(ErrCredentialNotFound, "unknown revocation nonce")
+	}
+
+	var cred GetCredentialResponse
+	if err := json.Unmarshal(is.credentials[claimID], &cred); err != nil {
+		return errors.Wrap(err, "failed to unmarshal credential")
+	}
+
+	cred.Revoked = true
+
+	rawCredential, err := json.Marshal(cred)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal credential")
+	}
+	is.credentials[claimID] = rawCredential
+
+	return nil
+}
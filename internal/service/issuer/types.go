@@ -2,16 +2,51 @@ package issuer
 
 import (
 	"encoding/json"
+	"fmt"
 	"math/big"
+	"net/http"
 	"time"
 
 	"gitlab.com/distributed_lab/logan/v3/errors"
 )
 
 var (
-	ErrUnexpectedStatusCode = errors.New("unexpected status code")
+	// ErrIssuerUnavailable is returned when the issuer node is unreachable or the circuit breaker is open
+	ErrIssuerUnavailable = errors.New("issuer node is unavailable")
 )
 
+// NodeError is the error response of the issuer node.
+type NodeError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("issuer node responded %d: %s", e.StatusCode, e.Message)
+}
+
+// Unavailable reports whether the issuer call failed because the issuer node is
+// unavailable rather than because of the request.
+func Unavailable(err error) bool {
+	cause := errors.Cause(err)
+	if cause == ErrIssuerUnavailable {
+		return true
+	}
+
+	nodeErr, ok := cause.(*NodeError)
+	return ok && unhealthy(nodeErr.StatusCode)
+}
+
+// unhealthy reports whether the issuer node status code counts as its failure.
+func unhealthy(statusCode int) bool {
+	return statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests
+}
+
+// errorResponse is the issuer node error body.
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
 type UUIDResponse struct {
 	Id string `json:"id"`
 }
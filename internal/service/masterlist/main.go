@@ -0,0 +1,141 @@
+package masterlist
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/rarimo/certificate-transparency-go/x509"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/algorithms"
+	"github.com/rarimo/passport-identity-provider/internal/service/certificates"
+	"github.com/rarimo/passport-identity-provider/internal/service/cms"
+)
+
+var (
+	OIDCSCAMasterList = asn1.ObjectIdentifier{2, 23, 136, 1, 1, 2}
+)
+
+// MasterList is the CSCA master list, see ICAO Doc 9303 part 12, section 9.
+type MasterList struct {
+	Version      int
+	Certificates []*x509.Certificate
+	// Signer is the master list signer certificate the signature was verified with
+	Signer *x509.Certificate
+	// Skipped are the errors of the listed certificates that could not be parsed
+	Skipped []error
+}
+
+type cscaMasterList struct {
+	Version  int
+	CertList []asn1.RawValue `asn1:"set"`
+}
+
+// Parse parses DER-encoded master list and verifies its signature. The signer certificate
+// is not validated, use VerifySigner to check it against the trust anchors.
+func Parse(raw []byte, registry *algorithms.Registry) (*MasterList, error) {
+	signedData, err := cms.ParseSignedData(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse signed data")
+	}
+
+	if !signedData.ContentType.Equal(OIDCSCAMasterList) {
+		return nil, fmt.Errorf("expected CSCA master list content type, got %s", signedData.ContentType)
+	}
+
+	if len(signedData.SignerInfos) != 1 {
+		return nil, fmt.Errorf("expected exactly one signer info, got %d", len(signedData.SignerInfos))
+	}
+	signer := signedData.SignerInfos[0]
+
+	signerCert, err := signedData.SignerCertificate(signer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get master list signer certificate")
+	}
+
+	if err := verifySignerInfo(signer, signerCert, signedData.Content, registry); err != nil {
+		return nil, errors.Wrap(err, "failed to verify master list signature")
+	}
+
+	list := cscaMasterList{}
+	rest, err := asn1.Unmarshal(signedData.Content, &list)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal CSCA master list")
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after CSCA master list")
+	}
+
+	masterList := MasterList{
+		Version:      list.Version,
+		Certificates: make([]*x509.Certificate, 0, len(list.CertList)),
+		Signer:       signerCert,
+		Skipped:      make([]error, 0),
+	}
+
+	for i, rawCert := range list.CertList {
+		cert, err := certificates.Parse(rawCert.FullBytes)
+		if err != nil {
+			masterList.Skipped = append(masterList.Skipped, errors.Wrap(err, fmt.Sprintf("failed to parse certificate %d", i)))
+			continue
+		}
+
+		masterList.Certificates = append(masterList.Certificates, cert)
+	}
+
+	return &masterList, nil
+}
+
+// VerifySigner validates the master list signer certificate chain against the trusted roots.
+// The CSCAs of the list itself are never trusted for it, anyone could then sign a master list
+// vouching for its own signer.
+func (m *MasterList) VerifySigner(roots *x509.CertPool) error {
+	if roots == nil {
+		return errors.New("master list anchors are required to verify the signer")
+	}
+
+	_, err := m.Signer.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return errors.Wrap(err, "invalid master list signer certificate")
+	}
+
+	return nil
+}
+
+func verifySignerInfo(signer cms.SignerInfo, cert *x509.Certificate, content []byte, registry *algorithms.Registry) error {
+	algorithmName, err := signer.AlgorithmName()
+	if err != nil {
+		return errors.Wrap(err, "failed to get signature algorithm")
+	}
+
+	algorithm, err := registry.Lookup(algorithmName, signer.AlgorithmParameters())
+	if err != nil {
+		return errors.Wrap(err, "failed to select signature algorithm")
+	}
+
+	digestHash, err := algorithms.HashFromOID(signer.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return errors.Wrap(err, "invalid digest algorithm")
+	}
+
+	messageDigest, err := signer.MessageDigest()
+	if err != nil {
+		return errors.Wrap(err, "failed to get message digest")
+	}
+
+	h := digestHash.New()
+	h.Write(content)
+	if !bytes.Equal(h.Sum(nil), messageDigest) {
+		return errors.New("message digest signed attribute is not equal to the content hash")
+	}
+
+	if err := algorithm.Verify(cert.PublicKey, signer.SignedAttributes, signer.Signature, signer.AlgorithmParameters()); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to verify %s signature", algorithm.Name))
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package masterlist
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	stdx509 "crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/rarimo/certificate-transparency-go/x509"
+)
+
+// newCertificate issues the CA certificate by the parent, a self-signed one if it is nil.
+func newCertificate(t *testing.T, name string, parent *stdx509.Certificate, parentKey *ecdsa.PrivateKey) (*stdx509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &stdx509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name, Country: []string{"UA"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              stdx509.KeyUsageCertSign | stdx509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	raw, err := stdx509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := stdx509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key
+}
+
+func parse(t *testing.T, cert *stdx509.Certificate) *x509.Certificate {
+	t.Helper()
+
+	parsed, err := x509.ParseCertificate(cert.Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return parsed
+}
+
+func TestVerifySigner(t *testing.T) {
+	anchor, anchorKey := newCertificate(t, "anchor", nil, nil)
+	signer, _ := newCertificate(t, "signer", anchor, anchorKey)
+
+	// a forged list carrying its own signer issuer
+	forgedCA, forgedCAKey := newCertificate(t, "forged CSCA", nil, nil)
+	forgedSigner, _ := newCertificate(t, "forged signer", forgedCA, forgedCAKey)
+	forged := MasterList{
+		Certificates: []*x509.Certificate{parse(t, forgedCA)},
+		Signer:       parse(t, forgedSigner),
+	}
+
+	anchors := x509.NewCertPool()
+	anchors.AddCert(parse(t, anchor))
+
+	if err := forged.VerifySigner(nil); err == nil {
+		t.Fatal("master list without anchors is accepted")
+	}
+	if err := forged.VerifySigner(anchors); err == nil {
+		t.Fatal("master list vouching for its own signer is accepted")
+	}
+
+	genuine := MasterList{
+		Certificates: []*x509.Certificate{parse(t, forgedCA)},
+		Signer:       parse(t, signer),
+	}
+	if err := genuine.VerifySigner(anchors); err != nil {
+		t.Fatalf("master list signed by the anchor is rejected: %v", err)
+	}
+}
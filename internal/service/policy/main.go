@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+)
+
+// DefaultName is the name of the policy used when none is configured.
+const DefaultName = "default"
+
+var ErrPolicyNotFound = errors.New("policy not found")
+
+// Subject is the registration data the policy rules are evaluated against.
+type Subject struct {
+	Age int64
+	// Country is the document signer certificate country
+	Country string
+	// DocumentType is the MRZ document code, empty unless the circuit commits to it
+	DocumentType string
+}
+
+// Policy is the named eligibility rule set, the empty country and document type sets allow any.
+type Policy struct {
+	Name          string
+	MinAge        int64
+	Countries     map[string]bool
+	DocumentTypes map[string]bool
+}
+
+// Evaluate checks the subject satisfies every rule of the policy.
+func (p *Policy) Evaluate(subject Subject) error {
+	if subject.Age < p.MinAge {
+		return fmt.Errorf("policy %s requires age %d at least, got %d", p.Name, p.MinAge, subject.Age)
+	}
+
+	if len(p.Countries) != 0 && !p.Countries[strings.ToUpper(subject.Country)] {
+		return fmt.Errorf("policy %s does not allow %s documents", p.Name, subject.Country)
+	}
+
+	if len(p.DocumentTypes) != 0 {
+		if subject.DocumentType == "" {
+			return fmt.Errorf("policy %s restricts document types, the proof must commit to the document type", p.Name)
+		}
+		if !p.DocumentTypes[strings.ToUpper(subject.DocumentType)] {
+			return fmt.Errorf("policy %s does not allow %s documents", p.Name, subject.DocumentType)
+		}
+	}
+
+	return nil
+}
+
+// Registry is the set of the configured policies.
+type Registry struct {
+	policies      map[string]*Policy
+	defaultPolicy string
+}
+
+// NewRegistry builds the policies of the config. Without any the only one is the default
+// policy of the allowed age, the default policy must be set when there are a few.
+func NewRegistry(cfg *config.PoliciesConfig, allowedAge int) (*Registry, error) {
+	if len(cfg.Policies) == 0 {
+		return &Registry{
+			policies: map[string]*Policy{
+				DefaultName: {Name: DefaultName, MinAge: int64(allowedAge)},
+			},
+			defaultPolicy: DefaultName,
+		}, nil
+	}
+
+	registry := Registry{
+		policies:      make(map[string]*Policy, len(cfg.Policies)),
+		defaultPolicy: cfg.Default,
+	}
+	for _, policyCfg := range cfg.Policies {
+		if _, ok := registry.policies[policyCfg.Name]; ok {
+			return nil, fmt.Errorf("policy %s is declared twice", policyCfg.Name)
+		}
+		if policyCfg.MinAge < 0 {
+			return nil, fmt.Errorf("policy %s min_age must not be negative", policyCfg.Name)
+		}
+
+		registry.policies[policyCfg.Name] = &Policy{
+			Name:          policyCfg.Name,
+			MinAge:        int64(policyCfg.MinAge),
+			Countries:     upperSet(policyCfg.Countries),
+			DocumentTypes: upperSet(policyCfg.DocumentTypes),
+		}
+	}
+
+	if registry.defaultPolicy == "" && len(cfg.Policies) == 1 {
+		registry.defaultPolicy = cfg.Policies[0].Name
+	}
+	if _, ok := registry.policies[registry.defaultPolicy]; registry.defaultPolicy != "" && !ok {
+		return nil, fmt.Errorf("default policy %s is not declared", registry.defaultPolicy)
+	}
+
+	return &registry, nil
+}
+
+// Lookup returns the policy by its name, the default one for the empty name.
+func (r *Registry) Lookup(name string) (*Policy, error) {
+	if name == "" {
+		if r.defaultPolicy == "" {
+			return nil, errors.New("policy must be selected, there is no default one")
+		}
+		name = r.defaultPolicy
+	}
+
+	policy, ok := r.policies[name]
+	if !ok {
+		return nil, errors.Wrap(ErrPolicyNotFound, fmt.Sprintf("%s is not one of %s", name, strings.Join(r.Names(), ", ")))
+	}
+
+	return policy, nil
+}
+
+// Names returns the sorted policy names.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.policies))
+	for name := range r.policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func upperSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[strings.ToUpper(value)] = true
+	}
+
+	return set
+}
@@ -0,0 +1,361 @@
+package revocation
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+)
+
+// Reasons of the claim revocations
+const (
+	ReasonFraud           = "fraud"
+	ReasonUserRequest     = "user_request"
+	ReasonCompromisedCSCA = "compromised_csca"
+)
+
+// Reasons lists the accepted revocation reasons.
+var Reasons = []string{ReasonFraud, ReasonUserRequest, ReasonCompromisedCSCA}
+
+// Selector selects the claims to revoke by exactly one of the claim ID, user ID, document
+// hash and document signer certificate serial, the latter may be narrowed by the country.
+type Selector struct {
+	ClaimID      *uuid.UUID
+	UserID       *uuid.UUID
+	DocumentHash *string
+	// DSSerial is the hex serial number of the document signer certificate
+	DSSerial  *string
+	DSCountry *string
+}
+
+// Validate checks exactly one of the selectors is set.
+func (s Selector) Validate() error {
+	selected := 0
+	for _, set := range []bool{s.ClaimID != nil, s.UserID != nil, s.DocumentHash != nil, s.DSSerial != nil} {
+		if set {
+			selected++
+		}
+	}
+	if selected != 1 {
+		return errors.New("exactly one of claim_id, user_id, document_hash and ds_serial must be set")
+	}
+
+	if s.DSCountry != nil && s.DSSerial == nil {
+		return errors.New("ds_country narrows ds_serial, it can not be used alone")
+	}
+	if s.DSSerial != nil {
+		if _, err := parseSerial(*s.DSSerial); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// String describes the selector for the audit records, e.g. ds_serial=1a2b.
+func (s Selector) String() string {
+	switch {
+	case s.ClaimID != nil:
+		return "claim_id=" + s.ClaimID.String()
+	case s.UserID != nil:
+		return "user_id=" + s.UserID.String()
+	case s.DocumentHash != nil:
+		return "document_hash=" + *s.DocumentHash
+	case s.DSSerial != nil && s.DSCountry != nil:
+		return fmt.Sprintf("ds_serial=%s,ds_country=%s", *s.DSSerial, strings.ToUpper(*s.DSCountry))
+	case s.DSSerial != nil:
+		return "ds_serial=" + *s.DSSerial
+	default:
+		return ""
+	}
+}
+
+// ValidateReason checks the reason is one of the Reasons.
+func ValidateReason(reason string) error {
+	for _, known := range Reasons {
+		if reason == known {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("reason %q is not one of %s", reason, strings.Join(Reasons, ", "))
+}
+
+// Revoker revokes the claims on the operator request and records the revocations.
+type Revoker struct {
+	db     data.MasterQ
+	issuer issuer.Issuer
+}
+
+func NewRevoker(db data.MasterQ, iss issuer.Issuer) *Revoker {
+	return &Revoker{
+		db:     db,
+		issuer: iss,
+	}
+}
+
+// Revoke revokes the credentials of the selected claims and returns the recorded revocations,
+// the already revoked credentials are skipped. The pending claim requests of the selection are
+// failed first, so the worker does not issue their claims afterwards. On error the revocations
+// made before it are returned along with it, revoking the same claims again picks up the rest.
+func (r *Revoker) Revoke(selector Selector, reason, operator string) ([]data.Revocation, error) {
+	if err := selector.Validate(); err != nil {
+		return nil, err
+	}
+	if err := ValidateReason(reason); err != nil {
+		return nil, err
+	}
+	if operator == "" {
+		return nil, errors.New("operator is required")
+	}
+
+	audit := data.Revocation{
+		Reason:   reason,
+		Operator: operator,
+		Selector: selector.String(),
+	}
+
+	requests, err := r.claimRequests(selector)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select claim requests")
+	}
+
+	var revocations []data.Revocation
+	for _, request := range requests {
+		revocation, err := r.cancel(request, audit)
+		if err != nil {
+			return revocations, errors.Wrap(err, "failed to cancel claim request", logan.F{"claim_request": request.ID})
+		}
+		if revocation != nil {
+			revocations = append(revocations, *revocation)
+		}
+	}
+
+	// the claims the worker has recorded before the cancellation are selected here
+	claims, err := r.claims(selector)
+	if err != nil {
+		return revocations, errors.Wrap(err, "failed to select claims")
+	}
+
+	for _, claim := range claims {
+		claimAudit := audit
+		claimAudit.ClaimID = &claim.ID
+		claimAudit.UserID = claim.UserID
+		claimAudit.DocumentHash = claim.DocumentHash
+
+		revocation, err := r.revoke(claimAudit)
+		if err != nil {
+			return revocations, errors.Wrap(err, "failed to revoke credential", logan.F{"claim_id": claim.ID})
+		}
+		if revocation != nil {
+			revocations = append(revocations, *revocation)
+		}
+	}
+
+	return revocations, nil
+}
+
+// cancel fails the pending claim request and records its revocation, the claim issued for the
+// claim request, but not recorded yet, is revoked then. The document lock is the one the worker
+// takes to update the claim request, so the worker either records the claim before or finds the
+// claim request failed. The claim request failed otherwise only has its claim revoked.
+func (r *Revoker) cancel(request data.ClaimRequest, audit data.Revocation) (*data.Revocation, error) {
+	audit.ClaimID = request.ClaimID
+	audit.ClaimRequestID = &request.ID
+	audit.UserID = request.UserID
+	audit.DocumentHash = request.DocumentHash
+
+	if request.Status != data.ClaimRequestPending {
+		return r.revoke(audit)
+	}
+
+	var cancelled *data.Revocation
+	err := r.db.New().Transaction(func(db data.MasterQ) error {
+		if err := db.ClaimRequest().LockDocument(request.DocumentHash); err != nil {
+			return errors.Wrap(err, "failed to lock document claim requests")
+		}
+
+		current, err := db.ClaimRequest().FilterBy("id", request.ID).Get()
+		if err != nil {
+			return errors.Wrap(err, "failed to get claim request")
+		}
+		if current == nil || current.Status == data.ClaimRequestIssued {
+			// the claim recorded meanwhile is selected with the claims
+			audit.ClaimID = nil
+			return nil
+		}
+		if current.Status != data.ClaimRequestPending {
+			audit.ClaimID = current.ClaimID
+			return nil
+		}
+
+		now := time.Now().UTC()
+		lastError := "cancelled by the revocation: " + audit.Reason
+		current.Status = data.ClaimRequestFailed
+		current.LeasedUntil = nil
+		current.LastError = &lastError
+		current.UpdatedAt = now
+		if err := db.ClaimRequest().Update(*current); err != nil {
+			return errors.Wrap(err, "failed to fail claim request")
+		}
+
+		if current.ClaimID != nil {
+			// the revocation of the issued claim is recorded by revoke
+			audit.ClaimID = current.ClaimID
+			return nil
+		}
+
+		audit.ID = uuid.New()
+		audit.Status = data.RevocationDone
+		audit.CreatedAt = now
+		if err := db.Revocation().Insert(audit); err != nil {
+			return errors.Wrap(err, "failed to insert revocation")
+		}
+
+		cancelled = &audit
+		return nil
+	})
+	if err != nil || audit.ClaimID == nil {
+		return cancelled, err
+	}
+
+	return r.revoke(audit)
+}
+
+// revoke records the pending revocation before the issuer revokes the credential and marks it
+// done after, so every revoked credential is audited. The pending revocation left by the failed
+// attempt is completed by the next one, the credential revoked otherwise is skipped.
+func (r *Revoker) revoke(audit data.Revocation) (*data.Revocation, error) {
+	cred, err := r.issuer.GetCredential(*audit.ClaimID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get credential")
+	}
+
+	revocation, err := r.db.New().Revocation().
+		FilterBy("claim_id", *audit.ClaimID).
+		FilterBy("status", data.RevocationPending).
+		Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get pending revocation")
+	}
+	if revocation == nil && cred.Revoked {
+		return nil, nil
+	}
+
+	if revocation == nil {
+		revocation = &audit
+		revocation.ID = uuid.New()
+		revocation.RevocationNonce = &cred.CredentialStatus.RevocationNonce
+		revocation.Status = data.RevocationPending
+		revocation.CreatedAt = time.Now().UTC()
+		if err := r.db.New().Revocation().Insert(*revocation); err != nil {
+			return nil, errors.Wrap(err, "failed to insert pending revocation")
+		}
+	}
+
+	if !cred.Revoked {
+		if err := r.issuer.RevokeClaim(*revocation.RevocationNonce); err != nil {
+			return nil, errors.Wrap(err, "failed to revoke claim")
+		}
+	}
+
+	// the claim status is recorded before the revocation is done, so the failed attempt
+	// leaves the revocation pending and the next one records it
+	claim, err := r.db.New().Claim().FilterBy("id", *revocation.ClaimID).Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get claim")
+	}
+	if claim != nil && claim.Status == data.ClaimActive {
+		claim.Status = data.ClaimRevoked
+		if err := r.db.New().Claim().Update(*claim); err != nil {
+			return nil, errors.Wrap(err, "failed to record revoked claim")
+		}
+	}
+
+	revocation.Status = data.RevocationDone
+	if err := r.db.New().Revocation().Update(*revocation); err != nil {
+		return nil, errors.Wrap(err, "failed to mark revocation done")
+	}
+
+	return revocation, nil
+}
+
+// claimRequests selects the pending claim requests and the ones with the claim issued, but not
+// recorded, as the claims of the latter are not selected with the claims.
+func (r *Revoker) claimRequests(selector Selector) ([]data.ClaimRequest, error) {
+	pending, err := filterClaimRequests(r.db.New().ClaimRequest().FilterBy("status", data.ClaimRequestPending), selector).Select()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select pending claim requests")
+	}
+
+	unrecorded, err := filterClaimRequests(r.db.New().ClaimRequest().FilterUnrecorded(), selector).Select()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select unrecorded claim requests")
+	}
+
+	requests := pending
+	for _, request := range unrecorded {
+		if request.Status != data.ClaimRequestPending {
+			requests = append(requests, request)
+		}
+	}
+
+	return requests, nil
+}
+
+func filterClaimRequests(requestsQ data.ClaimRequestQ, selector Selector) data.ClaimRequestQ {
+	switch {
+	case selector.ClaimID != nil:
+		return requestsQ.FilterBy("claim_id", *selector.ClaimID)
+	case selector.UserID != nil:
+		return requestsQ.FilterBy("user_id", *selector.UserID)
+	case selector.DocumentHash != nil:
+		return requestsQ.FilterBy("document_hash", *selector.DocumentHash)
+	case selector.DSSerial != nil:
+		serial, _ := parseSerial(*selector.DSSerial)
+		requestsQ = requestsQ.FilterBy("ds_serial", serial.Text(16))
+		if selector.DSCountry != nil {
+			requestsQ = requestsQ.FilterBy("ds_country", strings.ToUpper(*selector.DSCountry))
+		}
+	}
+
+	return requestsQ
+}
+
+func (r *Revoker) claims(selector Selector) ([]data.Claim, error) {
+	claimsQ := r.db.New().Claim()
+
+	switch {
+	case selector.ClaimID != nil:
+		claimsQ = claimsQ.FilterBy("id", *selector.ClaimID)
+	case selector.UserID != nil:
+		claimsQ = claimsQ.FilterBy("user_id", *selector.UserID)
+	case selector.DocumentHash != nil:
+		claimsQ = claimsQ.FilterBy("document_hash", *selector.DocumentHash)
+	case selector.DSSerial != nil:
+		serial, _ := parseSerial(*selector.DSSerial)
+		claimsQ = claimsQ.FilterBy("ds_serial", serial.Text(16))
+		if selector.DSCountry != nil {
+			claimsQ = claimsQ.FilterBy("ds_country", strings.ToUpper(*selector.DSCountry))
+		}
+	}
+
+	return claimsQ.Select()
+}
+
+// parseSerial parses the hex serial number, the bytes may be separated by colons.
+func parseSerial(serial string) (*big.Int, error) {
+	value, ok := new(big.Int).SetString(strings.ReplaceAll(serial, ":", ""), 16)
+	if !ok {
+		return nil, fmt.Errorf("ds_serial %q is not a hex number", serial)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,160 @@
+package revocation
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/iden3/go-iden3-core/v2/w3c"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/data/datatest"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+)
+
+const testIssuerDID = "did:iden3:readonly:tJWarsbwqiUxHm8BPi4aYSnnj54AbuR4D2RrhkykQ"
+
+// failingUpdates fails the revocation updates, as the lost connection after the issuer
+// has revoked the credential does.
+type failingUpdates struct {
+	data.MasterQ
+}
+
+func (q failingUpdates) New() data.MasterQ {
+	return failingUpdates{q.MasterQ.New()}
+}
+
+func (q failingUpdates) Revocation() data.RevocationQ {
+	return failingRevocationQ{q.MasterQ.Revocation()}
+}
+
+type failingRevocationQ struct {
+	data.RevocationQ
+}
+
+func (failingRevocationQ) Update(data.Revocation) error {
+	return errors.New("connection reset")
+}
+
+func newTestClaims(t *testing.T, db *datatest.DB, iss issuer.Issuer, userID uuid.UUID, serials ...string) []uuid.UUID {
+	t.Helper()
+
+	expiration := time.Now().Add(time.Hour)
+	ids := make([]uuid.UUID, len(serials))
+	for i, serial := range serials {
+		rawClaimID, err := iss.IssueVotingClaim(testIssuerDID, 1, true, &expiration, []byte{1}, big.NewInt(1), common.Address{}, userID, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids[i] = uuid.MustParse(rawClaimID)
+		db.Claims[ids[i]] = data.Claim{
+			ID:           ids[i],
+			UserID:       userID,
+			DocumentHash: "1",
+			DSSerial:     serial,
+			DSCountry:    "UA",
+			Status:       data.ClaimActive,
+			CreatedAt:    time.Now().Add(time.Duration(i) * time.Second),
+		}
+	}
+
+	return ids
+}
+
+func newTestIssuer(t *testing.T) *issuer.MemoryIssuer {
+	t.Helper()
+
+	did, err := w3c.ParseDID(testIssuerDID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return issuer.NewMemory(&config.IssuerConfig{DID: did, ClaimType: "VotingCredential"})
+}
+
+func TestRevoke(t *testing.T) {
+	db := datatest.New()
+	iss := newTestIssuer(t)
+	userID := uuid.New()
+	claimIDs := newTestClaims(t, db, iss, userID, "a1b", "ff")
+
+	serial, country := "0A:1B", "ua"
+	revocations, err := NewRevoker(db.MasterQ(), iss).Revoke(Selector{DSSerial: &serial, DSCountry: &country}, ReasonCompromisedCSCA, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revocations) != 1 || *revocations[0].ClaimID != claimIDs[0] || revocations[0].Selector != "ds_serial=0A:1B,ds_country=UA" {
+		t.Fatalf("unexpected revocations %+v", revocations)
+	}
+	if status := db.Revocations[revocations[0].ID].Status; status != data.RevocationDone {
+		t.Errorf("expected revocation done, got %s", status)
+	}
+	if status := db.Claims[claimIDs[0]].Status; status != data.ClaimRevoked {
+		t.Errorf("expected claim recorded as revoked, got %s", status)
+	}
+
+	// the revoked claim is skipped
+	revocations, err = NewRevoker(db.MasterQ(), iss).Revoke(Selector{UserID: &userID}, ReasonFraud, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revocations) != 1 || *revocations[0].ClaimID != claimIDs[1] || len(db.Revocations) != 2 {
+		t.Fatalf("unexpected revocations %+v", revocations)
+	}
+}
+
+func TestRevokeAuditsRevokedBeforeFailure(t *testing.T) {
+	db := datatest.New()
+	iss := newTestIssuer(t)
+	userID := uuid.New()
+	claimIDs := newTestClaims(t, db, iss, userID, "a1b")
+
+	_, err := NewRevoker(failingUpdates{db.MasterQ()}, iss).Revoke(Selector{ClaimID: &claimIDs[0]}, ReasonFraud, "alice")
+	if err == nil {
+		t.Fatal("expected revocation to fail")
+	}
+
+	cred, err := iss.GetCredential(claimIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cred.Revoked {
+		t.Fatal("expected credential revoked before the failure")
+	}
+	if len(db.Revocations) != 1 {
+		t.Fatalf("expected pending revocation recorded, got %d revocations", len(db.Revocations))
+	}
+
+	revocations, err := NewRevoker(db.MasterQ(), iss).Revoke(Selector{ClaimID: &claimIDs[0]}, ReasonFraud, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revocations) != 1 || len(db.Revocations) != 1 || db.Revocations[revocations[0].ID].Status != data.RevocationDone {
+		t.Fatalf("expected pending revocation completed, got %+v", db.Revocations)
+	}
+}
+
+func TestSelectorValidate(t *testing.T) {
+	claimID, serial, country := uuid.New(), "zz", "UA"
+
+	cases := map[string]Selector{
+		"none":          {},
+		"two":           {ClaimID: &claimID, UserID: &claimID},
+		"country alone": {DSCountry: &country},
+		"invalid":       {DSSerial: &serial},
+	}
+	for name, selector := range cases {
+		if err := selector.Validate(); err == nil {
+			t.Errorf("%s: expected selector rejected", name)
+		}
+	}
+
+	if err := ValidateReason("boredom"); err == nil {
+		t.Error("expected unknown reason rejected")
+	}
+}
@@ -1,15 +1,25 @@
 package service
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/go-chi/chi"
 	stateabi "github.com/iden3/contracts-abi/state/go/abi"
+	"github.com/rarimo/passport-identity-provider/internal/config"
 	"github.com/rarimo/passport-identity-provider/internal/data/pg"
+	"github.com/rarimo/passport-identity-provider/internal/service/algorithms"
 	"github.com/rarimo/passport-identity-provider/internal/service/api/handlers"
+	"github.com/rarimo/passport-identity-provider/internal/service/challenges"
+	"github.com/rarimo/passport-identity-provider/internal/service/crl"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuance"
 	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/internal/service/policy"
 	"github.com/rarimo/passport-identity-provider/internal/service/vault"
+	"github.com/rarimo/passport-identity-provider/internal/service/verifierstate"
 	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/logan/v3/errors"
 )
 
 func (s *service) router() chi.Router {
@@ -28,10 +38,55 @@ func (s *service) router() chi.Router {
 		s.log.WithError(err).Fatal("failed to init new vault client")
 	}
 
-	issuerLogin, issuerPassword, err := vaultClient.IssuerAuthData()
+	iss, err := NewIssuer(s.cfg, vaultClient)
+	if err != nil {
+		s.log.WithError(err).Fatal("failed to init issuer")
+	}
+
+	algorithmsRegistry := algorithms.NewDefaultRegistry()
+
+	verifierState, err := verifierstate.NewReloader(s.log.WithField("service", "verifier-state"), s.cfg.VerifierConfig(), algorithmsRegistry)
+	if err != nil {
+		s.log.WithError(err).Fatal("failed to load verifier state")
+	}
+
+	groth16Verifier, err := handlers.NewGroth16Verifier(s.cfg.VerifierConfig().Backend)
 	if err != nil {
-		s.log.WithError(err).Fatal("failed to get issuer auth data from the vault")
+		s.log.WithError(err).Fatal("failed to init Groth16 verifier")
+	}
+
+	policies, err := policy.NewRegistry(s.cfg.PoliciesConfig(), s.cfg.VerifierConfig().AllowedAge)
+	if err != nil {
+		s.log.WithError(err).Fatal("failed to init policies")
+	}
+
+	crlChecker := crl.NewChecker(s.log.WithField("service", "crl"), s.cfg.CRLConfig(), verifierState)
+	if err := crlChecker.Refresh(context.Background()); err != nil {
+		s.log.WithError(err).Error("failed to load CRLs")
 	}
+	// new CSCAs may sign the CRLs that were rejected before
+	verifierState.OnReload(func(*verifierstate.State) {
+		if err := crlChecker.Refresh(context.Background()); err != nil {
+			s.log.WithError(err).Error("failed to refresh CRLs after reload")
+		}
+	})
+	go crlChecker.Run(context.Background())
+	go verifierState.Run(context.Background())
+
+	issuanceWorker := issuance.NewWorker(
+		s.log.WithField("service", "issuance"),
+		s.cfg.IssuanceConfig(),
+		pg.NewMasterQ(s.cfg.DB()),
+		iss, vaultClient,
+	)
+	go issuanceWorker.Run(context.Background())
+
+	challengeCleaner := challenges.NewCleaner(
+		s.log.WithField("service", "challenges"),
+		s.cfg.ChallengeConfig(),
+		pg.NewMasterQ(s.cfg.DB()),
+	)
+	go challengeCleaner.Run(context.Background())
 
 	r := chi.NewRouter()
 
@@ -43,21 +98,53 @@ func (s *service) router() chi.Router {
 			handlers.CtxMasterQ(pg.NewMasterQ(s.cfg.DB())),
 			handlers.CtxVerifierConfig(s.cfg.VerifierConfig()),
 			handlers.CtxStateContract(stateContract),
-			handlers.CtxIssuer(issuer.New(
-				s.cfg.Log().WithField("service", "issuer"),
-				s.cfg.IssuerConfig(),
-				issuerLogin, issuerPassword,
-			)),
+			handlers.CtxIssuer(iss),
 			handlers.CtxVaultClient(vaultClient),
 			handlers.CtxEthClient(ethCli),
+			handlers.CtxAlgorithms(algorithmsRegistry),
+			handlers.CtxVerifierState(verifierState),
+			handlers.CtxCRLChecker(crlChecker),
+			handlers.CtxGroth16Verifier(groth16Verifier),
+			handlers.CtxChallengeConfig(s.cfg.ChallengeConfig()),
+			handlers.CtxPolicies(policies),
 		),
 	)
 	r.Route("/integrations/identity-provider-service", func(r chi.Router) {
 		r.Route("/v1", func(r chi.Router) {
 			r.Post("/create-identity", handlers.CreateIdentity)
 			r.Get("/gist-data", handlers.GetGistData)
+			r.Get("/challenge", handlers.GetChallenge)
+			r.Get("/claim-requests/{id}", handlers.GetClaimRequest)
+			r.Get("/claims", handlers.GetClaims)
+			r.Get("/claims/{id}", handlers.GetClaim)
+
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(handlers.AdminAuth(s.cfg.AdminConfig().Operators))
+				r.Post("/reload", handlers.ReloadVerifierState)
+				r.Post("/revocations", handlers.RevokeClaims)
+			})
 		})
 	})
 
 	return r
 }
+
+// NewIssuer returns the issuer of the configured backend, the node one authenticates
+// with the credentials from the vault.
+func NewIssuer(cfg config.Config, vaultClient *vault.VaultClient) (issuer.Issuer, error) {
+	if cfg.IssuerConfig().Backend == config.IssuerBackendMemory {
+		cfg.Log().Warn("credentials are issued by the in-memory issuer, they are lost on restart")
+		return issuer.NewMemory(cfg.IssuerConfig()), nil
+	}
+
+	issuerLogin, issuerPassword, err := vaultClient.IssuerAuthData()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get issuer auth data from the vault")
+	}
+
+	return issuer.New(
+		cfg.Log().WithField("service", "issuer"),
+		cfg.IssuerConfig(),
+		issuerLogin, issuerPassword,
+	), nil
+}
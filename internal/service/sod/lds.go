@@ -0,0 +1,88 @@
+package sod
+
+import (
+	"crypto"
+	"encoding/asn1"
+	"fmt"
+
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/algorithms"
+	"github.com/rarimo/passport-identity-provider/resources"
+)
+
+const (
+	DG1  = 1
+	DG2  = 2
+	DG14 = 14
+	DG15 = 15
+
+	maxDataGroupNumber = 16
+)
+
+var (
+	ErrDataGroupNotFound = errors.New("data group hash not found")
+)
+
+// SecurityObject is the parsed LDS security object: the hashes of the data groups
+// present in the document keyed by the data group number.
+type SecurityObject struct {
+	Version         int
+	HashAlgorithm   crypto.Hash
+	DataGroupHashes map[int][]byte
+}
+
+func ParseSecurityObject(der []byte) (*SecurityObject, error) {
+	lds := resources.LDSSecurityObject{}
+	rest, err := asn1.Unmarshal(der, &lds)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal LDS security object")
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after LDS security object")
+	}
+
+	// version 0 is LDS v1.7, version 1 is LDS v1.8 with the version info
+	if lds.Version != 0 && lds.Version != 1 {
+		return nil, fmt.Errorf("unsupported LDS security object version %d", lds.Version)
+	}
+
+	hash, err := algorithms.HashFromOID(lds.HashAlgorithm.Algorithm)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid LDS hash algorithm")
+	}
+
+	hashes := make(map[int][]byte, len(lds.DataGroupHashValues))
+	for _, dg := range lds.DataGroupHashValues {
+		if dg.DataGroupNumber < 1 || dg.DataGroupNumber > maxDataGroupNumber {
+			return nil, fmt.Errorf("invalid data group number %d", dg.DataGroupNumber)
+		}
+
+		if _, ok := hashes[dg.DataGroupNumber]; ok {
+			return nil, fmt.Errorf("duplicate hash of DG%d", dg.DataGroupNumber)
+		}
+
+		if len(dg.DataGroupHashValue) != hash.Size() {
+			return nil, fmt.Errorf("DG%d hash length is %d, expected %d for %s",
+				dg.DataGroupNumber, len(dg.DataGroupHashValue), hash.Size(), hash)
+		}
+
+		hashes[dg.DataGroupNumber] = dg.DataGroupHashValue
+	}
+
+	return &SecurityObject{
+		Version:         lds.Version,
+		HashAlgorithm:   hash,
+		DataGroupHashes: hashes,
+	}, nil
+}
+
+// DataGroupHash returns the hash of the data group by its number.
+func (s *SecurityObject) DataGroupHash(number int) ([]byte, error) {
+	hash, ok := s.DataGroupHashes[number]
+	if !ok {
+		return nil, errors.Wrap(ErrDataGroupNotFound, fmt.Sprintf("DG%d", number))
+	}
+
+	return hash, nil
+}
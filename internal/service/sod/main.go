@@ -0,0 +1,109 @@
+package sod
+
+import (
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/rarimo/certificate-transparency-go/x509"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/cms"
+)
+
+// efSODTag is the ICAO Doc 9303 application tag wrapping the EF.SOD content info
+const efSODTag = 23
+
+var (
+	OIDLDSSecurityObject = asn1.ObjectIdentifier{2, 23, 136, 1, 1, 1}
+)
+
+// SOD is the document security object split into the values the verification works with.
+type SOD struct {
+	SignedAttributes    []byte
+	Algorithm           string
+	AlgorithmParameters []byte
+	Signature           []byte
+	Certificate         *x509.Certificate
+	EncapsulatedContent []byte
+}
+
+// Decode decodes hex or base64 encoded EF.SOD.
+func Decode(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+
+	if raw, err := hex.DecodeString(strings.TrimPrefix(encoded, "0x")); err == nil {
+		return raw, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("EF.SOD is neither hex nor base64 encoded")
+	}
+
+	return raw, nil
+}
+
+// Parse parses the EF.SOD file contents, with or without the application tag.
+func Parse(raw []byte) (*SOD, error) {
+	contentInfo, err := unwrapEFSOD(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unwrap EF.SOD")
+	}
+
+	signedData, err := cms.ParseSignedData(contentInfo)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse signed data")
+	}
+
+	if !signedData.ContentType.Equal(OIDLDSSecurityObject) {
+		return nil, fmt.Errorf("expected LDS security object content type, got %s", signedData.ContentType)
+	}
+
+	// Doc 9303 requires exactly one signer, the document signer
+	if len(signedData.SignerInfos) != 1 {
+		return nil, fmt.Errorf("expected exactly one signer info, got %d", len(signedData.SignerInfos))
+	}
+	signer := signedData.SignerInfos[0]
+
+	cert, err := signedData.SignerCertificate(signer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get document signer certificate")
+	}
+
+	algorithm, err := signer.AlgorithmName()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get signature algorithm")
+	}
+
+	return &SOD{
+		SignedAttributes:    signer.SignedAttributes,
+		Algorithm:           algorithm,
+		AlgorithmParameters: signer.AlgorithmParameters(),
+		Signature:           signer.Signature,
+		Certificate:         cert,
+		EncapsulatedContent: signedData.Content,
+	}, nil
+}
+
+func unwrapEFSOD(raw []byte) ([]byte, error) {
+	var wrapper asn1.RawValue
+	rest, err := asn1.Unmarshal(raw, &wrapper)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal ASN.1")
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after EF.SOD")
+	}
+
+	switch {
+	case wrapper.Class == asn1.ClassApplication && wrapper.Tag == efSODTag:
+		return wrapper.Bytes, nil
+	case wrapper.Class == asn1.ClassUniversal && wrapper.Tag == asn1.TagSequence:
+		return wrapper.FullBytes, nil
+	default:
+		return nil, fmt.Errorf("unexpected EF.SOD tag %d of class %d", wrapper.Tag, wrapper.Class)
+	}
+}
@@ -0,0 +1,193 @@
+package truststore
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rarimo/certificate-transparency-go/x509"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+var (
+	ErrIssuerCountryMissing = errors.New("document signer certificate issuer has no country")
+	ErrCSCANotFound         = errors.New("no CSCA certificate found for the document signer")
+)
+
+// CountryMismatchError is returned when the CSCA identified by the authority key
+// identifier belongs to another country than the document signer issuer.
+type CountryMismatchError struct {
+	IssuerCountry string
+	CSCACountry   string
+}
+
+func (e *CountryMismatchError) Error() string {
+	return fmt.Sprintf("document signer issuer country %s does not match CSCA country %s", e.IssuerCountry, e.CSCACountry)
+}
+
+// Store is the set of CSCA certificates indexed by country and subject key identifier.
+type Store struct {
+	byCountry map[string][]*x509.Certificate
+	byKeyID   map[string][]*x509.Certificate
+	bySubject map[string][]*x509.Certificate
+	size      int
+}
+
+// Resolution describes how the CSCA of the document signer certificate was looked up.
+type Resolution struct {
+	Country        string
+	AuthorityKeyID string
+	// MatchedBy is either authority_key_id or country
+	MatchedBy  string
+	Candidates int
+	CSCA       *x509.Certificate
+	// Rejected are the reasons the candidates were not accepted, by their subjects and serials
+	Rejected map[string]string
+}
+
+// New indexes the certificates, the ones without the subject country are skipped
+// and returned, as they can not be matched against any document signer.
+func New(certs []*x509.Certificate) (*Store, []*x509.Certificate) {
+	store := Store{
+		byCountry: make(map[string][]*x509.Certificate),
+		byKeyID:   make(map[string][]*x509.Certificate),
+		bySubject: make(map[string][]*x509.Certificate),
+	}
+	skipped := make([]*x509.Certificate, 0)
+
+	for _, cert := range certs {
+		country := certCountry(cert.Subject.Country)
+		if country == "" {
+			skipped = append(skipped, cert)
+			continue
+		}
+
+		store.byCountry[country] = append(store.byCountry[country], cert)
+		if len(cert.SubjectKeyId) != 0 {
+			keyID := hex.EncodeToString(cert.SubjectKeyId)
+			store.byKeyID[keyID] = append(store.byKeyID[keyID], cert)
+		}
+		store.bySubject[string(cert.RawSubject)] = append(store.bySubject[string(cert.RawSubject)], cert)
+		store.size++
+	}
+
+	return &store, skipped
+}
+
+// Size returns the number of indexed certificates.
+func (s *Store) Size() int {
+	return s.size
+}
+
+// Countries returns the indexed countries in alphabetical order.
+func (s *Store) Countries() []string {
+	countries := make([]string, 0, len(s.byCountry))
+	for country := range s.byCountry {
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+
+	return countries
+}
+
+// Issuers returns the CSCAs with the key identifier, or with the DER-encoded subject
+// when the key identifier is empty or unknown.
+func (s *Store) Issuers(subject, keyID []byte) []*x509.Certificate {
+	if len(keyID) != 0 {
+		if issuers, ok := s.byKeyID[hex.EncodeToString(keyID)]; ok {
+			return issuers
+		}
+	}
+
+	return s.bySubject[string(subject)]
+}
+
+// Resolve finds the CSCA that issued the document signer certificate. Candidates are
+// selected by the authority key identifier, or by the issuer country when it is absent,
+// and only the CSCAs of the document signer issuer country are accepted.
+func (s *Store) Resolve(cert *x509.Certificate) (*Resolution, error) {
+	resolution := Resolution{
+		Country:        certCountry(cert.Issuer.Country),
+		AuthorityKeyID: hex.EncodeToString(cert.AuthorityKeyId),
+		Rejected:       make(map[string]string),
+	}
+	if resolution.Country == "" {
+		return &resolution, ErrIssuerCountryMissing
+	}
+
+	candidates := s.byCountry[resolution.Country]
+	resolution.MatchedBy = "country"
+
+	if resolution.AuthorityKeyID != "" {
+		byKeyID := s.byKeyID[resolution.AuthorityKeyID]
+		sameCountry := make([]*x509.Certificate, 0, len(byKeyID))
+		for _, csca := range byKeyID {
+			if certCountry(csca.Subject.Country) == resolution.Country {
+				sameCountry = append(sameCountry, csca)
+			}
+		}
+
+		if len(sameCountry) == 0 && len(byKeyID) != 0 {
+			resolution.MatchedBy = "authority_key_id"
+			resolution.Candidates = len(byKeyID)
+			return &resolution, &CountryMismatchError{
+				IssuerCountry: resolution.Country,
+				CSCACountry:   certCountry(byKeyID[0].Subject.Country),
+			}
+		}
+		if len(sameCountry) != 0 {
+			candidates = sameCountry
+			resolution.MatchedBy = "authority_key_id"
+		}
+	}
+
+	resolution.Candidates = len(candidates)
+
+	for _, csca := range candidates {
+		roots := x509.NewCertPool()
+		roots.AddCert(csca)
+
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:     roots,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			resolution.Rejected[fmt.Sprintf("%s (%s)", csca.Subject, csca.SerialNumber)] = err.Error()
+			continue
+		}
+
+		resolution.CSCA = csca
+		return &resolution, nil
+	}
+
+	return &resolution, ErrCSCANotFound
+}
+
+// Fields returns the resolution details for logging.
+func (r *Resolution) Fields() logan.F {
+	fields := logan.F{
+		"ds_country":       r.Country,
+		"ds_authority_key": r.AuthorityKeyID,
+		"csca_matched_by":  r.MatchedBy,
+		"csca_candidates":  r.Candidates,
+	}
+	if r.CSCA != nil {
+		fields["csca_subject"] = r.CSCA.Subject.String()
+		fields["csca_serial"] = r.CSCA.SerialNumber.String()
+	}
+	if len(r.Rejected) != 0 {
+		fields["csca_rejected"] = r.Rejected
+	}
+
+	return fields
+}
+
+func certCountry(countries []string) string {
+	if len(countries) == 0 {
+		return ""
+	}
+
+	return strings.ToUpper(strings.TrimSpace(countries[0]))
+}
@@ -0,0 +1,156 @@
+package verifierstate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rarimo/certificate-transparency-go/x509"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/service/algorithms"
+	"github.com/rarimo/passport-identity-provider/internal/service/certificates"
+	"github.com/rarimo/passport-identity-provider/internal/service/circuits"
+	"github.com/rarimo/passport-identity-provider/internal/service/groth16"
+	"github.com/rarimo/passport-identity-provider/internal/service/masterlist"
+	"github.com/rarimo/passport-identity-provider/internal/service/truststore"
+)
+
+// InvalidStateError is returned when the verifier files were read, but the verification
+// keys or trust anchors in them are invalid.
+type InvalidStateError struct {
+	err error
+}
+
+func (e *InvalidStateError) Error() string {
+	return e.err.Error()
+}
+
+// Load reads and validates the verification keys and trust anchors.
+func Load(log *logan.Entry, cfg *config.VerifierConfig, registry *algorithms.Registry) (*State, error) {
+	files, err := cfg.ReadFiles()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read verifier files")
+	}
+
+	circuitsRegistry, err := newCircuits(cfg.AllCircuits(), files)
+	if err != nil {
+		return nil, &InvalidStateError{err: errors.Wrap(err, "failed to build circuits registry")}
+	}
+
+	store, err := newTrustStore(log, files, registry)
+	if err != nil {
+		return nil, &InvalidStateError{err: errors.Wrap(err, "failed to build trust store")}
+	}
+
+	return &State{
+		Circuits:   circuitsRegistry,
+		TrustStore: store,
+		LoadedAt:   time.Now(),
+	}, nil
+}
+
+func newCircuits(configs []config.CircuitConfig, files *config.VerifierFiles) (*circuits.Registry, error) {
+	list := make([]circuits.Circuit, 0, len(configs))
+	for _, cfg := range configs {
+		circuit := circuits.Circuit{
+			ID:              cfg.ID,
+			Algorithm:       cfg.Algorithm,
+			VerificationKey: files.VerificationKeys[cfg.ID],
+			Default:         cfg.Default,
+			DeprecatedAt:    cfg.DeprecatedAt,
+			Schema:          circuits.DefaultSchema,
+		}
+		if len(cfg.PubSignals) != 0 {
+			circuit.Schema = make([]circuits.Signal, len(cfg.PubSignals))
+			for i, signal := range cfg.PubSignals {
+				circuit.Schema[i] = circuits.Signal{Name: signal.Name, Type: circuits.SignalType(signal.Type)}
+			}
+		}
+
+		preparedKey, err := groth16.ParseVerifyingKey(circuit.VerificationKey)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid %s verification key", circuit.ID))
+		}
+		circuit.PreparedKey = preparedKey
+
+		list = append(list, circuit)
+	}
+
+	return circuits.NewRegistry(list...)
+}
+
+// newTrustStore loads the master certificates and indexes them by country and key identifier.
+func newTrustStore(log *logan.Entry, files *config.VerifierFiles, registry *algorithms.Registry) (*truststore.Store, error) {
+	masterCerts, err := loadMasterCerts(log, files, registry)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load master certificates")
+	}
+
+	store, skipped := truststore.New(masterCerts)
+	for _, cert := range skipped {
+		log.WithField("subject", cert.Subject.String()).Warn("skipped master certificate without country")
+	}
+
+	log.WithFields(logan.F{
+		"certificates": store.Size(),
+		"countries":    len(store.Countries()),
+	}).Info("trust store built")
+
+	return store, nil
+}
+
+// loadMasterCerts collects the CSCA certificates from the PEM bundle and the master lists,
+// master lists are accepted only if their signature and signer certificate are valid.
+func loadMasterCerts(log *logan.Entry, files *config.VerifierFiles, registry *algorithms.Registry) ([]*x509.Certificate, error) {
+	masterCerts, errs := certificates.ParsePEMBundle(files.MasterCerts)
+	for _, err := range errs {
+		log.WithError(err).Warn("skipped master certificate")
+	}
+
+	var anchors *x509.CertPool
+	if len(files.MasterListAnchors) != 0 {
+		anchorCerts, errs := certificates.ParsePEMBundle(files.MasterListAnchors)
+		if len(errs) != 0 {
+			return nil, errors.Wrap(errs[0], "failed to parse master list anchors")
+		}
+		if len(anchorCerts) == 0 {
+			return nil, errors.New("no master list anchors found")
+		}
+
+		anchors = x509.NewCertPool()
+		for _, cert := range anchorCerts {
+			anchors.AddCert(cert)
+		}
+	}
+
+	for path, raw := range files.MasterLists {
+		ml, err := masterlist.Parse(raw, registry)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to parse master list %s", path))
+		}
+
+		if err := ml.VerifySigner(anchors); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to verify master list %s", path))
+		}
+
+		for _, err := range ml.Skipped {
+			log.WithError(err).WithField("master_list", path).Warn("skipped master list certificate")
+		}
+
+		log.WithFields(logan.F{
+			"master_list": path,
+			"signer":      ml.Signer.Subject.String(),
+			"loaded":      len(ml.Certificates),
+		}).Info("master list loaded")
+
+		masterCerts = append(masterCerts, ml.Certificates...)
+	}
+
+	if len(masterCerts) == 0 {
+		return nil, errors.New("no master certificates loaded")
+	}
+
+	return masterCerts, nil
+}
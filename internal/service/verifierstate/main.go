@@ -0,0 +1,190 @@
+package verifierstate
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rarimo/certificate-transparency-go/x509"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/service/algorithms"
+	"github.com/rarimo/passport-identity-provider/internal/service/circuits"
+	"github.com/rarimo/passport-identity-provider/internal/service/truststore"
+)
+
+// watchDebounce lets the files being replaced settle before reloading
+const watchDebounce = time.Second
+
+// State is the verifier trust material, it is never modified once loaded.
+type State struct {
+	Circuits   *circuits.Registry
+	TrustStore *truststore.Store
+	LoadedAt   time.Time
+}
+
+// Reloader holds the current State and replaces it as a whole on reload.
+type Reloader struct {
+	log      *logan.Entry
+	cfg      *config.VerifierConfig
+	registry *algorithms.Registry
+
+	current atomic.Pointer[State]
+	// mu serializes reloads
+	mu       sync.Mutex
+	onReload []func(*State)
+}
+
+// NewReloader loads the initial State, the service can not start without it.
+func NewReloader(log *logan.Entry, cfg *config.VerifierConfig, registry *algorithms.Registry) (*Reloader, error) {
+	r := &Reloader{
+		log:      log,
+		cfg:      cfg,
+		registry: registry,
+	}
+
+	state, err := Load(log, cfg, registry)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load verifier state")
+	}
+	r.current.Store(state)
+
+	return r, nil
+}
+
+// Current returns the State to be used for the whole request.
+func (r *Reloader) Current() *State {
+	return r.current.Load()
+}
+
+// Issuers looks up the CSCAs in the current trust store.
+func (r *Reloader) Issuers(subject, keyID []byte) []*x509.Certificate {
+	return r.Current().TrustStore.Issuers(subject, keyID)
+}
+
+// OnReload registers the hook called after every successful reload. The hooks are
+// called asynchronously, so they may do the network calls without blocking the reload.
+func (r *Reloader) OnReload(hook func(*State)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.onReload = append(r.onReload, hook)
+}
+
+// Reload loads the new State and swaps it in. If the new material is invalid the
+// current State is kept and the error is returned, it is *InvalidStateError for the
+// material that failed validation.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, err := Load(r.log, r.cfg, r.registry)
+	if err != nil {
+		return errors.Wrap(err, "failed to load verifier state")
+	}
+
+	r.current.Store(state)
+	r.log.WithFields(logan.F{
+		"circuits":     state.Circuits.Len(),
+		"master_certs": state.TrustStore.Size(),
+	}).Info("verifier state reloaded")
+
+	hooks := make([]func(*State), len(r.onReload))
+	copy(hooks, r.onReload)
+	go func() {
+		for _, hook := range hooks {
+			hook(state)
+		}
+	}()
+
+	return nil
+}
+
+// Run reloads the State on SIGHUP and, if enabled, on the files change until the context is done.
+func (r *Reloader) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var changes <-chan struct{}
+	if r.cfg.WatchFiles {
+		watched, err := r.watch(ctx)
+		if err != nil {
+			r.log.WithError(err).Error("failed to watch verifier files")
+		}
+		changes = watched
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reload("SIGHUP")
+		case <-changes:
+			r.reload("files change")
+		}
+	}
+}
+
+func (r *Reloader) reload(trigger string) {
+	if err := r.Reload(); err != nil {
+		r.log.WithError(err).WithField("trigger", trigger).Error("failed to reload verifier state, keeping the current one")
+	}
+}
+
+// watch notifies about the changes of the verifier files. The directories are watched
+// rather than the files, as the files are often replaced by renaming.
+func (r *Reloader) watch(ctx context.Context) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create watcher")
+	}
+
+	paths := make(map[string]bool)
+	for _, path := range r.cfg.Paths() {
+		paths[path] = true
+
+		dir := filepath.Dir(path)
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, errors.Wrap(err, "failed to watch "+dir)
+		}
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer watcher.Close()
+
+		debounce := time.NewTimer(watchDebounce)
+		debounce.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-watcher.Events:
+				if paths[filepath.Clean(event.Name)] && event.Op != fsnotify.Chmod {
+					debounce.Reset(watchDebounce)
+				}
+			case err := <-watcher.Errors:
+				r.log.WithError(err).Warn("verifier files watcher error")
+			case <-debounce.C:
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
@@ -1,47 +1,28 @@
 package resources
 
-import "encoding/asn1"
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+)
 
-type DigestAttribute struct {
-	ID     asn1.ObjectIdentifier
-	Digest []asn1.RawValue `asn1:"set"`
+// PSSParameters reflects RSASSA-PSS-params, see RFC 4055, section 3.1.
+// Omitted hash and mask generation algorithms default to SHA-1 and MGF1 with SHA-1.
+type PSSParameters struct {
+	Hash         pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:0"`
+	MGF          pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:1"`
+	SaltLength   int                      `asn1:"optional,explicit,tag:2,default:20"`
+	TrailerField int                      `asn1:"optional,explicit,tag:3,default:1"`
 }
 
-type EncapsulatedData struct {
+// LDSSecurityObject is the encapsulated content of the document SOD, see ICAO Doc 9303 part 10.
+type LDSSecurityObject struct {
 	Version             int
-	PrivateKeyAlgorithm asn1.RawValue
-	PrivateKey          struct {
-		El1 struct {
-			Integer  int
-			OctetStr asn1.RawValue
-		}
-		El2 struct {
-			Integer  int
-			OctetStr asn1.RawValue
-		}
-		El3 struct {
-			Integer  int
-			OctetStr asn1.RawValue
-		}
-		El4 struct {
-			Integer  int
-			OctetStr asn1.RawValue
-		}
-		El5 struct {
-			Integer  int
-			OctetStr asn1.RawValue
-		}
-		El6 struct {
-			Integer  int
-			OctetStr asn1.RawValue
-		}
-		El7 struct {
-			Integer  int
-			OctetStr asn1.RawValue
-		}
-		El8 struct {
-			Integer  int
-			OctetStr asn1.RawValue
-		}
-	}
+	HashAlgorithm       pkix.AlgorithmIdentifier
+	DataGroupHashValues []DataGroupHash
+	LDSVersionInfo      asn1.RawValue `asn1:"optional"`
+}
+
+type DataGroupHash struct {
+	DataGroupNumber    int
+	DataGroupHashValue []byte
 }
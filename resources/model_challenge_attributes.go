@@ -0,0 +1,17 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+import "time"
+
+type ChallengeAttributes struct {
+	// Hex-encoded 8-byte active authentication challenge derived from the nonce
+	AaChallenge string `json:"aa_challenge"`
+	// Hex-encoded uncompressed chip authentication terminal ephemeral public key, the private key is kept by the service
+	CaTerminalPublicKey *string   `json:"ca_terminal_public_key,omitempty"`
+	ExpiresAt           time.Time `json:"expires_at"`
+	// Decimal BN254 field element the registration proof must commit to
+	Nonce string `json:"nonce"`
+}
@@ -4,8 +4,27 @@
 
 package resources
 
+import "time"
+
 type ClaimAttributes struct {
-	ClaimId   string  `json:"claim_id"`
-	IssuerDid string  `json:"issuer_did"`
-	UserId    *string `json:"user_id,omitempty"`
+	// Mechanisms the chip proved it is genuine by
+	AntiCloningMechanisms []string `json:"anti_cloning_mechanisms"`
+	ClaimId               string   `json:"claim_id"`
+	// Credential issuance time
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	// Whether the credential is expired
+	Expired *bool `json:"expired,omitempty"`
+	// Credential expiration time, omitted if it does not expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	IssuerDid string     `json:"issuer_did"`
+	// Eligibility policy the registration satisfied
+	Policy string `json:"policy"`
+	// Revocation nonce of the credential, omitted in the list for the claims issued before the statuses were recorded
+	RevocationNonce *int64 `json:"revocation_nonce,omitempty"`
+	// Whether the credential is revoked
+	Revoked *bool `json:"revoked,omitempty"`
+	// Credential status, superseded if the credential is revoked as the document was registered again. The list reports the status recorded by the service, the claim route asks the issuer.
+	Status  string `json:"status"`
+	UserDid string `json:"user_did"`
+	UserId  string `json:"user_id"`
 }
@@ -0,0 +1,31 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+type ClaimRequest struct {
+	Key
+	Attributes ClaimRequestAttributes `json:"attributes"`
+}
+type ClaimRequestResponse struct {
+	Data     ClaimRequest `json:"data"`
+	Included Included     `json:"included"`
+}
+
+type ClaimRequestListResponse struct {
+	Data     []ClaimRequest `json:"data"`
+	Included Included       `json:"included"`
+	Links    *Links         `json:"links"`
+}
+
+// MustClaimRequest - returns ClaimRequest from include collection.
+// if entry with specified key does not exist - returns nil
+// if entry with specified key exists but type or ID mismatches - panics
+func (c *Included) MustClaimRequest(key Key) *ClaimRequest {
+	var claimRequest ClaimRequest
+	if c.tryFindEntry(key, &claimRequest) {
+		return &claimRequest
+	}
+	return nil
+}
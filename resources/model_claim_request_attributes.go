@@ -0,0 +1,24 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+import "time"
+
+type ClaimRequestAttributes struct {
+	// Mechanisms the chip proved it is genuine by
+	AntiCloningMechanisms []string `json:"anti_cloning_mechanisms"`
+	// Claim issuance attempts made so far
+	Attempts int64 `json:"attempts"`
+	// Issued claim ID, set once the status is issued
+	ClaimId   *string   `json:"claim_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Issuer DID of the claim, set once the status is issued
+	IssuerDid *string `json:"issuer_did,omitempty"`
+	// Eligibility policy the registration satisfied
+	Policy string `json:"policy"`
+	// Claim issuance status: pending, issued or failed
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
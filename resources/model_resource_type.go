@@ -8,6 +8,9 @@ type ResourceType string
 
 // List of ResourceType
 const (
-	CLAIMS     ResourceType = "claims"
-	GIST_DATAS ResourceType = "gist_datas"
+	CHALLENGES     ResourceType = "challenges"
+	CLAIMS         ResourceType = "claims"
+	CLAIM_REQUESTS ResourceType = "claim_requests"
+	GIST_DATAS     ResourceType = "gist_datas"
+	REVOCATIONS    ResourceType = "revocations"
 )
@@ -0,0 +1,31 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+type Revocation struct {
+	Key
+	Attributes RevocationAttributes `json:"attributes"`
+}
+type RevocationResponse struct {
+	Data     Revocation `json:"data"`
+	Included Included   `json:"included"`
+}
+
+type RevocationListResponse struct {
+	Data     []Revocation `json:"data"`
+	Included Included     `json:"included"`
+	Links    *Links       `json:"links"`
+}
+
+// MustRevocation - returns Revocation from include collection.
+// if entry with specified key does not exist - returns nil
+// if entry with specified key exists but type or ID mismatches - panics
+func (c *Included) MustRevocation(key Key) *Revocation {
+	var revocation Revocation
+	if c.tryFindEntry(key, &revocation) {
+		return &revocation
+	}
+	return nil
+}
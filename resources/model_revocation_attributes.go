@@ -0,0 +1,25 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+import "time"
+
+type RevocationAttributes struct {
+	// Claim ID of the revoked credential, not set for the claim request cancelled before the issuance
+	ClaimId *string `json:"claim_id,omitempty"`
+	// Claim request cancelled by the revocation
+	ClaimRequestId *string   `json:"claim_request_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	DocumentHash   string    `json:"document_hash"`
+	// Operator whose token authenticated the revocation, `cli:<user>` for the command line ones
+	Operator string `json:"operator"`
+	// Revocation reason: fraud, user_request or compromised_csca
+	Reason string `json:"reason"`
+	// Revocation nonce of the revoked credential
+	RevocationNonce *int64 `json:"revocation_nonce,omitempty"`
+	// How the operator selected the claim, e.g. ds_serial=1a2b
+	Selector string `json:"selector"`
+	UserId   string `json:"user_id"`
+}